@@ -1,7 +1,14 @@
 package SecretGopher
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"math/rand"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -11,11 +18,17 @@ func init() {
 	InitHandlerGroup(1)
 }
 
+// rejected tells if o is an Error matching target
+func rejected(o Output, target error) bool {
+	err, ok := o.(Error)
+	return ok && errors.Is(err, target)
+}
+
 func TestNewGame(t *testing.T) {
 	G := NewGame()
 
-	if G.out == nil {
-		t.Error("Output channel of game is nil")
+	if G.data == nil {
+		t.Error("Data of game is nil")
 	}
 
 	if G.in == nil {
@@ -181,7 +194,7 @@ func TestHandling(t *testing.T) {
 	}
 	// test the special power system
 	if enacted == FascistPolicy {
-		o = G.SpecialPower(p, Investigate, 0)
+		o = G.SpecialPower(p, Investigate, (p+1)%10) // the president cannot investigate himself
 		switch o.(type) {
 		case Ok:
 			info := o.(Ok).Info
@@ -212,3 +225,1672 @@ func TestHandling(t *testing.T) {
 		}
 	}
 }
+
+func TestStateFor(t *testing.T) {
+	G := NewGame()
+	for i := 0; i < 5; i++ {
+		G.AddPlayer()
+	}
+	G.Start()
+	// find out the real roles of the players
+	roles := G.data.roles
+	var fascist, hitler int8
+	for i, r := range roles {
+		switch r {
+		case FascistParty:
+			fascist = int8(i)
+		case Hitler:
+			hitler = int8(i)
+		}
+	}
+
+	// the spectator view must not reveal anything
+	for i, r := range G.StateFor(Spectator).Roles {
+		if r != UnknownRole {
+			t.Errorf("Spectator view revealed the role of player %d", i)
+		}
+	}
+
+	// the fascist knows everybody on his team
+	view := G.StateFor(fascist)
+	if view.Roles[hitler] != Hitler || view.Roles[fascist] != FascistParty {
+		t.Error("Fascist view does not reveal the fascist team", view.Roles)
+	}
+
+	// in a 5 player game hitler knows his teammate
+	view = G.StateFor(hitler)
+	if view.Roles[fascist] != FascistParty {
+		t.Error("Hitler view does not reveal his teammate in a 5 player game", view.Roles)
+	}
+
+	// a liberal only knows his own role
+	for i, r := range roles {
+		if r != LiberalParty {
+			continue
+		}
+		for j, known := range G.StateFor(int8(i)).Roles {
+			if j == i && known != LiberalParty {
+				t.Errorf("Player %d does not know his own role", i)
+			} else if j != i && known != UnknownRole {
+				t.Errorf("Player %d knows the role of player %d", i, j)
+			}
+		}
+	}
+
+	// the government can poll the hand it holds, nobody else sees it
+	p := G.data.president
+	c := G.data.nextLiving(p)
+	G.MakeChancellor(p, c)
+	for i := int8(0); i < 5; i++ {
+		G.Vote(i, Ja)
+	}
+	hands := func(president, chancellor int) {
+		t.Helper()
+		for i := int8(0); i < 5; i++ {
+			want := 0
+			switch i {
+			case p:
+				want = president
+			case c:
+				want = chancellor
+			}
+			if h := G.StateFor(i).Hand; len(h) != want {
+				t.Errorf("Player %d sees a hand of %d policies, expected %d", i, len(h), want)
+			}
+		}
+		if G.StateFor(Spectator).Hand != nil {
+			t.Error("A spectator saw the hand of the government")
+		}
+	}
+	hands(3, 0)
+	G.PolicyDiscard(p, 0)
+	hands(0, 2)
+	G.data.tracks[FascistPolicy] = G.data.rules.VetoUnlock
+	G.Veto(c)
+	hands(2, 2)
+}
+
+func TestDeck(t *testing.T) {
+	d := newDeck(rand.New(rand.NewSource(1)), []int8{6, 11})
+	if len(d.pile) != 17 {
+		t.Fatal("Wrong deck size, expected 17 got", len(d.pile))
+	}
+	// play legislative sessions until the deck gets reshuffled a few times
+	for round := 0; round < 12; round++ {
+		hand := d.draw(3)
+		d.discard(hand[0], hand[1])
+		d.enact(hand[2])
+		d.refill()
+		if len(d.pile) < 3 {
+			t.Error("Deck was not refilled, cards left:", len(d.pile))
+		}
+		if n := len(d.pile) + len(d.discarded) + len(d.enacted); n != 17 {
+			t.Error("Policies went missing, expected 17 got", n)
+		}
+		if len(d.enacted) > 14 {
+			break
+		}
+	}
+	// the peeked cards are the ones drawn next
+	p := d.peek()
+	if h := d.draw(3); h[0] != p[0] || h[1] != p[1] || h[2] != p[2] {
+		t.Error("Peek does not match the drawn cards")
+	}
+}
+
+func TestSeededGames(t *testing.T) {
+	var states [2]GameState
+	var games [2]Game
+	for i := range games {
+		games[i] = NewGameWithOptions(Options{Seed: 42})
+		for j := 0; j < 7; j++ {
+			games[i].AddPlayer()
+		}
+		games[i].Start()
+		states[i] = games[i].StateFor(Spectator)
+	}
+	if states[0].President != states[1].President {
+		t.Error("Games with the same seed picked different presidents")
+	}
+	for i := range games[0].data.roles {
+		if games[0].data.roles[i] != games[1].data.roles[i] {
+			t.Error("Games with the same seed dealt different roles")
+		}
+	}
+	for i := range games[0].data.deck.pile {
+		if games[0].data.deck.pile[i] != games[1].data.deck.pile[i] {
+			t.Error("Games with the same seed shuffled different decks")
+		}
+	}
+	if seed, ok := games[0].Seed(); !ok || seed != 42 {
+		t.Error("Wrong seed reported, expected 42 got", seed)
+	}
+	crypto := NewGameWithOptions(Options{CryptoRand: true})
+	if _, ok := crypto.Seed(); ok {
+		t.Error("A crypto/rand game cannot be reproduced from a seed")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	G := NewGameWithOptions(Options{Seed: 7})
+	for i := 0; i < 5; i++ {
+		G.AddPlayer()
+	}
+	G.Start()
+	p := G.data.president
+	c := (p + 1) % 5
+	G.MakeChancellor(p, c)
+	G.MakeChancellor(p, c) // rejected, must not be logged
+	for i := int8(0); i < 5; i++ {
+		G.Vote(i, Ja)
+	}
+
+	l := G.Log()
+	if len(l.Entries) != 12 {
+		t.Fatal("Wrong log length, expected 12 got", len(l.Entries))
+	}
+	for i, e := range l.Entries {
+		if e.Seq != uint64(i+1) {
+			t.Error("Wrong sequence number, expected", i+1, "got", e.Seq)
+		}
+	}
+
+	R, err := Replay(l)
+	if err != nil {
+		t.Fatal("Replay failed:", err)
+	}
+	if R.data.state != G.data.state || R.data.president != G.data.president || R.data.chancellor != G.data.chancellor {
+		t.Error("Replayed game diverged from the original")
+	}
+	for i := range G.data.roles {
+		if R.data.roles[i] != G.data.roles[i] {
+			t.Error("Replayed game dealt different roles")
+		}
+	}
+	for i := range G.data.policyChoice {
+		if R.data.policyChoice[i] != G.data.policyChoice[i] {
+			t.Error("Replayed game drew different policies")
+		}
+	}
+
+	if _, err := Replay(Log{Entries: l.Entries}); err == nil {
+		t.Error("Replaying an unseeded log should fail")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	G := NewGameWithOptions(Options{Seed: 7})
+	for i := 0; i < 5; i++ {
+		G.AddPlayer()
+	}
+	G.Start()
+	p := G.data.president
+	G.MakeChancellor(p, (p+1)%5)
+	for i := int8(0); i < 5; i++ {
+		G.Vote(i, Ja)
+	}
+
+	b, err := G.Snapshot()
+	if err != nil {
+		t.Fatal("Snapshot failed:", err)
+	}
+	R, err := RestoreGame(b)
+	if err != nil {
+		t.Fatal("Restore failed:", err)
+	}
+	if !reflect.DeepEqual(G.StateFor(p), R.StateFor(p)) {
+		t.Error("Restored game diverged from the original")
+	}
+	if len(R.Log().Entries) != len(G.Log().Entries) {
+		t.Error("Restored game lost its log")
+	}
+	// the restored snapshot must be identical to the original one
+	if b2, _ := R.Snapshot(); string(b2) != string(b) {
+		t.Error("Snapshot of the restored game differs from the original")
+	}
+	// the random sources must continue from the same point
+	if G.data.rng.Int63() != R.data.rng.Int63() {
+		t.Error("Restored game random source diverged from the original")
+	}
+
+	if _, err := RestoreGame([]byte(`{"Version":0}`)); err == nil {
+		t.Error("Restoring an unknown version should fail")
+	}
+	// snapshots holding seats, roles or policies out of range, or inconsistent with the state of the game,
+	// are rejected before they reach a handler
+	if G.data.state != presidentLegislation {
+		t.Fatal("Expected the snapshot to be taken during a legislative session, got", G.data.state)
+	}
+	// a fascist track filled up by the policies of the pile
+	rest, filled := []Policy{}, []Policy{}
+	for _, p := range G.data.deck.pile {
+		if p == FascistPolicy && len(filled) < 6 {
+			filled = append(filled, p)
+		} else {
+			rest = append(rest, p)
+		}
+	}
+	for _, fields := range []map[string]interface{}{
+		{"Roles": []int{9, 0, 0, 0, 0}},
+		{"President": 7},
+		{"Chancellor": -5},
+		{"NextPresident": 5},
+		{"OldGov": []int{9, -1}},
+		{"Killed": []int{5}},
+		{"Votes": []int{4, 0, 0, 0, 0}},
+		{"Voted": 6},
+		{"Voted": 4},
+		{"PolicyChoice": []int{7, 0, 0}},
+		{"PolicyChoice": []int{0, 1}},
+		{"Pile": []int{-1}},
+		{"Pile": []int{}},
+		{"Tracks": []int{-1, 0}},
+		{"Tracks": []int{0, 1}},
+		{"Tracks": []int{0, 6}, "Enacted": filled, "Pile": rest},
+	} {
+		var s map[string]interface{}
+		json.Unmarshal(b, &s)
+		for field, value := range fields {
+			s[field] = value
+		}
+		tampered, _ := json.Marshal(s)
+		if _, err := RestoreGame(tampered); err == nil {
+			t.Error("Restoring a snapshot with invalid", fields, "should fail")
+		}
+	}
+}
+
+// fakeClock is a Clock that only moves when told to
+type fakeClock struct {
+	mut    sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at   time.Time
+	f    func()
+	done bool
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	t := &fakeTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (t *fakeTimer) Stop() bool {
+	stopped := !t.done
+	t.done = true
+	return stopped
+}
+
+// Advance moves the clock forward by d and runs the timers that expired
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mut.Lock()
+	c.now = c.now.Add(d)
+	var expired []*fakeTimer
+	for _, t := range c.timers {
+		if !t.done && !t.at.After(c.now) {
+			t.done = true
+			expired = append(expired, t)
+		}
+	}
+	c.mut.Unlock()
+	for _, t := range expired {
+		t.f()
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	clock := &fakeClock{}
+	var applied []TimeoutApplied
+	G := NewGameWithOptions(Options{
+		Seed:      3,
+		Timeouts:  Timeouts{Nomination: time.Minute, Election: time.Minute, MissingVote: Nein},
+		OnTimeout: func(t TimeoutApplied) { applied = append(applied, t) },
+		Clock:     clock,
+	})
+	for i := 0; i < 5; i++ {
+		G.AddPlayer()
+	}
+	G.Start()
+
+	// nothing happens before the deadline
+	clock.Advance(30 * time.Second)
+	if len(applied) != 0 {
+		t.Fatal("Timeout applied before the deadline")
+	}
+	// the president does not nominate anybody in time
+	clock.Advance(time.Minute)
+	if len(applied) != 1 {
+		t.Fatal("Nomination timeout was not applied")
+	}
+	if _, ok := applied[0].Result.(Ok).Info.(ElectionStart); !ok {
+		t.Fatal("Expected ElectionStart after the nomination timeout, got", applied[0].Result)
+	}
+
+	// two players vote in favour, the others do not vote in time
+	G.Vote(0, Ja)
+	G.Vote(1, Ja)
+	clock.Advance(time.Minute)
+	if len(applied) != 2 {
+		t.Fatal("Election timeout was not applied")
+	}
+	if len(applied[1].Players) != 3 {
+		t.Error("Expected 3 late voters, got", applied[1].Players)
+	}
+	if _, ok := applied[1].Result.(Ok).Info.(NextPresident); !ok {
+		t.Error("Expected NextPresident after the election timeout, got", applied[1].Result)
+	}
+	if s := G.StateFor(Spectator); s.ElectionTracker != 1 {
+		t.Error("Expected the election tracker to advance, got", s.ElectionTracker)
+	}
+	// the timeout is part of the log and gets replayed
+	R, err := Replay(G.Log())
+	if err != nil {
+		t.Fatal("Replay failed:", err)
+	}
+	if R.data.state != G.data.state || R.data.president != G.data.president || R.data.eTracker != G.data.eTracker {
+		t.Error("Replayed game diverged from the original")
+	}
+
+	// late voters can be made to vote in favour too
+	applied, clock = nil, &fakeClock{}
+	G = NewGameWithOptions(Options{
+		Seed:      3,
+		Timeouts:  Timeouts{Election: time.Minute, MissingVote: Ja},
+		OnTimeout: func(t TimeoutApplied) { applied = append(applied, t) },
+		Clock:     clock,
+	})
+	for i := 0; i < 5; i++ {
+		G.AddPlayer()
+	}
+	G.Start()
+	p := G.data.president
+	G.MakeChancellor(p, (p+1)%5)
+	G.Vote(0, Nein)
+	clock.Advance(time.Minute)
+	if len(applied) != 1 || len(applied[0].Commands) != 4 {
+		t.Fatal("Expected 4 votes cast by the election timeout, got", applied)
+	}
+	if _, ok := applied[0].Result.(Ok).Info.(LegislationPresident); !ok {
+		t.Error("Expected the government to be elected by the late votes, got", applied[0].Result)
+	}
+}
+
+func TestDo(t *testing.T) {
+	G, H := NewGame(), NewGame()
+	if o, err := G.Do(context.Background(), Command{Kind: AddPlayerCommand}); err != nil || o != (Ok{Info: PlayerRegistered(0)}) {
+		t.Error("Expected PlayerRegistered(0), got", o, err)
+	}
+
+	// a cancelled context gives up right away
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := G.Do(ctx, Command{Kind: AddPlayerCommand}); err != context.Canceled {
+		t.Error("Expected context.Canceled, got", err)
+	}
+
+	// a start with too few players is answered, not left hanging
+	if o, err := G.Do(context.Background(), Command{Kind: StartCommand}); err != nil || !rejected(o, Invalid{Reason{Code: NotEnoughPlayers}}) {
+		t.Error("Expected NotEnoughPlayers, got", o, err)
+	}
+
+	// an expired deadline gives up right away too
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Millisecond)
+	defer cancel()
+	if _, err := G.Do(ctx, Command{Kind: AddPlayerCommand}); err != context.DeadlineExceeded {
+		t.Error("Expected context.DeadlineExceeded, got", err)
+	}
+
+	// the other games sharing the handler must not be affected
+	if o := H.AddPlayer(); o != (Ok{Info: PlayerRegistered(0)}) {
+		t.Error("Expected PlayerRegistered(0), got", o)
+	}
+	if o := G.AddPlayer(); o != (Ok{Info: PlayerRegistered(1)}) && o != (Ok{Info: PlayerRegistered(2)}) {
+		t.Error("Expected PlayerRegistered, got", o)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	G := NewGameWithOptions(Options{Seed: 7})
+	for i := 0; i < 5; i++ {
+		G.AddPlayer()
+	}
+	var subs [5]<-chan Event
+	for i := range subs {
+		subs[i] = G.Subscribe(int8(i))
+	}
+	spectator := G.SubscribeSpectator()
+	G.Start()
+	p := G.data.president
+	G.MakeChancellor(p, (p+1)%5)
+	var last Output
+	for i := int8(0); i < 5; i++ {
+		last = G.Vote(i, Ja)
+	}
+	if G.data.state != presidentLegislation {
+		t.Fatal("Expected the legislative session to start")
+	}
+	// the reply to the last voter is redacted like the events he gets
+	if hand := last.(Ok).Info.(LegislationPresident).Hand; (p == 4) != (hand != nil) {
+		t.Error("The last voter got the wrong hand:", hand)
+	}
+
+	// everybody gets GameStart, ElectionStart, 4 VoteRegistered and LegislationPresident
+	for i, ch := range append(subs[:], spectator) {
+		if len(ch) != 7 {
+			t.Fatal("Expected 7 events for subscriber", i, "got", len(ch))
+		}
+		for j := 0; j < 6; j++ {
+			<-ch
+		}
+		e := <-ch
+		hand := e.Output.(Ok).Info.(LegislationPresident).Hand
+		if int8(i) == p && len(hand) != 3 {
+			t.Error("The president did not get his hand")
+		} else if int8(i) != p && hand != nil {
+			t.Error("Subscriber", i, "got the hand of the president")
+		}
+	}
+
+	// a subscriber that does not keep up is dropped
+	H := NewGameWithOptions(Options{EventBuffer: 1})
+	slow := H.SubscribeSpectator()
+	H.AddPlayer()
+	H.AddPlayer()
+	if _, ok := <-slow; !ok {
+		t.Error("Expected the first event to be delivered")
+	}
+	if _, ok := <-slow; ok {
+		t.Error("Expected the slow subscriber to be dropped")
+	}
+}
+
+func TestClose(t *testing.T) {
+	G := NewGame()
+	G.AddPlayer()
+	events := G.SubscribeSpectator()
+	h := G.handler
+	G.Close()
+	if o := G.AddPlayer(); !rejected(o, GameClosed{}) {
+		t.Error("Expected GameClosed after Close, got", o)
+	}
+	<-events // PlayerRegistered
+	if _, ok := <-events; ok {
+		t.Error("Expected the subscription to be closed")
+	}
+	G.Close() // closing twice is harmless
+
+	// a handler left without games is stopped and removed from the pool
+	poolMut.Lock()
+	n := h.len
+	poolMut.Unlock()
+	if n == 0 {
+		<-h.done
+	}
+
+	// Shutdown stops every handler and closes their games
+	H := NewGame()
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal("Shutdown failed:", err)
+	}
+	if o := H.AddPlayer(); !rejected(o, GameClosed{}) {
+		t.Error("Expected GameClosed after Shutdown, got", o)
+	}
+	// the pool keeps working after Shutdown
+	I := NewGame()
+	if o := I.AddPlayer(); o != (Ok{Info: PlayerRegistered(0)}) {
+		t.Error("Expected PlayerRegistered(0) after Shutdown, got", o)
+	}
+
+	// finished games give their handler back, but can still be inspected
+	before := runtime.NumGoroutine()
+	var ended []Game
+	for seed := int64(0); seed < 20; seed++ {
+		G := NewGameWithOptions(Options{Seed: seed})
+		bots := map[int8]Bot{}
+		for p := int8(0); p < 5; p++ {
+			G.AddPlayer()
+			bots[p] = NewRandomBot(seed + int64(p))
+		}
+		G.Start()
+		if _, err := NewDriver(&G, bots).Run(context.Background()); err != nil {
+			t.Fatal("The bots could not finish the game:", err)
+		}
+		ended = append(ended, G)
+	}
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before; {
+		if time.Now().After(deadline) {
+			t.Fatal("The handlers of the finished games were not stopped:", runtime.NumGoroutine(), "goroutines, expected", before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for _, G := range ended {
+		if G.Phase() != GameEndPhase || len(G.Log().Entries) == 0 || len(G.StateFor(0).Roles) != 5 {
+			t.Fatal("A finished game cannot be inspected anymore")
+		}
+	}
+	ended[0].Close()
+	if o := ended[0].AddPlayer(); !rejected(o, GameClosed{}) {
+		t.Error("Expected GameClosed after closing a finished game, got", o)
+	}
+}
+
+func TestManager(t *testing.T) {
+	clock := &fakeClock{}
+	M := NewManager(ManagerOptions{IdleTTL: time.Hour, SweepInterval: time.Minute, Clock: clock})
+	defer M.Close()
+	id, G, err := M.Create(Options{})
+	if err != nil {
+		t.Fatal("Create failed:", err)
+	}
+	G.AddPlayer()
+	clock.Advance(30 * time.Minute)
+	other, _, _ := M.Create(Options{})
+	if _, _, err := M.Create(Options{Rules: Rules{MinPlayers: 2, MaxPlayers: 4}}); err == nil {
+		t.Error("Expected Create to reject invalid rules")
+	}
+
+	if g, ok := M.Get(id); !ok || g != G {
+		t.Error("Could not get the game by its ID")
+	}
+	list := M.List()
+	if len(list) != 2 || list[0].ID != id || list[1].ID != other {
+		t.Fatal("Wrong list of games", list)
+	}
+	if list[0].Players != 1 || list[0].Phase != "waitingPlayers" {
+		t.Error("Wrong summary", list[0])
+	}
+	if found, g, ok := M.GetByCode(strings.ToLower(list[0].Code)); !ok || found != id || g != G {
+		t.Error("Could not get the game by its code")
+	}
+
+	// the first game expires, the second one does not
+	clock.Advance(31 * time.Minute)
+	if _, ok := M.Get(id); ok {
+		t.Error("Idle game was not evicted")
+	}
+	if _, ok := M.Get(other); !ok {
+		t.Error("Game was evicted too early")
+	}
+	if o := G.AddPlayer(); !rejected(o, GameClosed{}) {
+		t.Error("Expected the evicted game to be closed, got", o)
+	}
+}
+
+func TestIdentities(t *testing.T) {
+	G := NewGameWithOptions(Options{Seed: 7})
+	ids := []string{"alice", "bob", "carol", "dave", "erin"}
+	for i, id := range ids {
+		if o := G.Join(id, strings.ToUpper(id)); o != (Ok{Info: PlayerRegistered(i)}) {
+			t.Error("Expected PlayerRegistered, got", o)
+		}
+	}
+	if o := G.Join("alice", "Alice again"); !rejected(o, Invalid{}) {
+		t.Error("Expected Invalid on a duplicate ID, got", o)
+	}
+	if s := G.Seat("carol"); s != 2 {
+		t.Error("Wrong seat for carol, expected 2 got", s)
+	}
+	if s := G.Seat("mallory"); s != NotSet {
+		t.Error("Expected NotSet for an unknown ID, got", s)
+	}
+	G.Start()
+	state := G.StateFor(Spectator)
+	if len(state.Players) != 5 || state.Players[4] != (Player{ID: "erin", Name: "ERIN"}) {
+		t.Error("Wrong identities in GameState", state.Players)
+	}
+
+	// commands can be addressed by ID
+	president := ids[state.President]
+	chancellor := ids[(state.President+1)%5]
+	o, _ := G.Do(context.Background(), Command{Kind: MakeChancellorCommand, CallerID: president, TargetID: chancellor})
+	if _, ok := o.(Ok).Info.(ElectionStart); !ok {
+		t.Fatal("Expected ElectionStart, got", o)
+	}
+	if o, _ := G.Do(context.Background(), Command{Kind: VoteCommand, CallerID: "mallory", Vote: Ja}); !rejected(o, Invalid{}) {
+		t.Error("Expected Invalid for an unknown ID, got", o)
+	}
+	if o, _ := G.Do(context.Background(), Command{Kind: VoteCommand, CallerID: "bob", Vote: Ja}); o != (Ok{Info: VoteRegistered{}}) {
+		t.Error("Expected VoteRegistered, got", o)
+	}
+	if G.data.votes[1] != Ja {
+		t.Error("The vote of bob was not registered")
+	}
+
+	// the IDs are logged and replayed
+	R, err := Replay(G.Log())
+	if err != nil {
+		t.Fatal("Replay failed:", err)
+	}
+	if R.Seat("erin") != 4 || R.data.votes[1] != Ja {
+		t.Error("Replayed game diverged from the original")
+	}
+	// the log records the seats the IDs stand for
+	l := G.Log().Entries
+	if c := l[len(l)-2].Command; c.Caller != state.President || c.Proposal != (state.President+1)%5 || c.CallerID != president {
+		t.Error("Wrong seats logged for a command sent by ID:", c)
+	}
+	if c := l[len(l)-1].Command; c.Caller != 1 {
+		t.Error("Wrong seat logged for a vote sent by ID:", c)
+	}
+
+	// private feedback reaches the player who sent the command by ID
+	rules := OfficialRules()
+	rules.Tables[0].Powers = [][]SpecialPowers{LiberalPolicy: {Peek}, FascistPolicy: {Peek}}
+	G = NewGameWithOptions(Options{Seed: 11, Rules: rules})
+	for _, id := range ids {
+		G.Join(id, id)
+	}
+	G.Start()
+	p := G.data.president
+	c := (p + 1) % 5
+	G.MakeChancellor(p, c)
+	for i := int8(0); i < 5; i++ {
+		G.Vote(i, Ja)
+	}
+	G.PolicyDiscard(p, 0)
+	G.PolicyDiscard(c, 0)
+	mine, theirs := G.Subscribe(p), G.Subscribe(c)
+	if o, _ := G.Do(context.Background(), Command{Kind: SpecialPowerCommand, CallerID: ids[p], Power: Peek}); !isOk(o) {
+		t.Fatal("Expected the peek to be accepted, got", o)
+	}
+	if e := <-mine; e.Output.(Ok).Info.(SpecialPowerFeedback).Feedback == nil {
+		t.Error("The president did not get the peeked policies")
+	}
+	if e := <-theirs; e.Output.(Ok).Info.(SpecialPowerFeedback).Feedback != nil {
+		t.Error("The peeked policies reached the chancellor")
+	}
+}
+
+func TestLobby(t *testing.T) {
+	G := NewGameWithOptions(Options{AutoStart: true})
+	for _, id := range []string{"host", "a", "b", "c", "d", "e"} {
+		G.Join(id, id)
+	}
+	if o := G.StartBy(1); !rejected(o, Unauthorized{}) {
+		t.Error("Expected Unauthorized when a guest starts the game, got", o)
+	}
+	if o := G.Kick(1, 2); !rejected(o, Unauthorized{}) {
+		t.Error("Expected Unauthorized when a guest kicks a player, got", o)
+	}
+
+	// kicking "a" moves everybody after him up by one seat
+	o := G.Kick(0, 1)
+	if left, ok := o.(Ok).Info.(PlayerLeft); !ok || !left.Kicked || left.Player.ID != "a" {
+		t.Fatal("Expected PlayerLeft, got", o)
+	}
+	if G.Seat("b") != 1 || G.Seat("e") != 4 || G.Seat("a") != NotSet {
+		t.Error("Seats were not compacted")
+	}
+
+	// when the host leaves, the player in seat 0 takes over
+	G.Leave(0)
+	if s := G.StateFor(Spectator); s.Host != 0 || s.Players[0].ID != "b" {
+		t.Error("Wrong host after the host left", s.Host, s.Players)
+	}
+	G.Join("f", "f")
+
+	// the game starts on its own once everybody is ready
+	for i := int8(0); i < 4; i++ {
+		if o := G.SetReady(i, true); o.(Ok).Info.(ReadyChanged).Seat != i {
+			t.Error("Expected ReadyChanged, got", o)
+		}
+	}
+	if _, ok := G.SetReady(4, true).(Ok).Info.(GameStart); !ok {
+		t.Error("Expected the game to start once everybody is ready")
+	}
+	if o := G.Leave(2); !rejected(o, WrongPhase{}) {
+		t.Error("Expected WrongPhase when leaving a running game, got", o)
+	}
+	// the game also starts on its own when the last player who was not ready leaves or is kicked
+	for _, kick := range []bool{false, true} {
+		G := NewGameWithOptions(Options{AutoStart: true})
+		for i := 0; i < 6; i++ {
+			G.AddPlayer()
+		}
+		for i := int8(0); i < 5; i++ {
+			G.SetReady(i, true)
+		}
+		var o Output
+		if kick {
+			o = G.Kick(0, 5)
+		} else {
+			o = G.Leave(5)
+		}
+		if _, ok := o.(Ok).Info.(GameStart); !ok {
+			t.Error("Expected the game to start once the last player who was not ready was gone, got", o)
+		}
+	}
+
+	// games that started on their own can be replayed
+	R, err := Replay(G.Log())
+	if err != nil {
+		t.Fatal("Replay failed:", err)
+	}
+	if !reflect.DeepEqual(R.StateFor(Spectator), G.StateFor(Spectator)) {
+		t.Error("Replayed game diverged from the original")
+	}
+}
+
+func TestDeadPlayers(t *testing.T) {
+	G := NewGameWithOptions(Options{Seed: 13})
+	for i := 0; i < 7; i++ {
+		G.AddPlayer()
+	}
+	G.Start()
+	// put the game right before an execution, with a liberal chancellor
+	var victim int8 = NotSet
+	for p, r := range G.data.roles {
+		if r == LiberalParty && int8(p) != G.data.president && victim == NotSet {
+			victim = int8(p)
+		}
+	}
+	G.data.state = specialExecution
+	G.data.chancellor = NotSet
+	for p, r := range G.data.roles {
+		if r == LiberalParty && int8(p) != G.data.president && int8(p) != victim {
+			G.data.chancellor = int8(p)
+			break
+		}
+	}
+	president := G.data.president
+	if o := G.SpecialPower(president, Execution, 7); !rejected(o, Invalid{}) {
+		t.Error("Expected InvalidInput when executing an empty seat, got", o)
+	}
+	o := G.SpecialPower(president, Execution, victim)
+	if _, ok := o.(Ok).Info.(SpecialPowerFeedback); !ok {
+		t.Fatal("Expected SpecialPowerFeedback, got", o)
+	}
+	s := G.StateFor(Spectator)
+	if len(s.Killed) != 1 || s.Killed[0] != victim || len(s.Living) != 6 || search(s.Living, victim) {
+		t.Error("Wrong dead and living players", s.Killed, s.Living)
+	}
+	if s.President == victim {
+		t.Error("A dead player became president")
+	}
+
+	// the dead can neither be nominated nor vote, and the quorum only counts the living
+	if o := G.MakeChancellor(s.President, victim); !rejected(o, Invalid{}) {
+		t.Error("Expected InvalidInput when nominating a dead player, got", o)
+	}
+	var chancellor int8
+	for chancellor = 0; chancellor == victim || chancellor == s.President || search(G.data.oldGov, chancellor); chancellor++ {
+	}
+	G.MakeChancellor(s.President, chancellor)
+	if o := G.Vote(victim, Ja); !rejected(o, Unauthorized{}) {
+		t.Error("Expected Unauthorized when a dead player votes, got", o)
+	}
+	for _, p := range s.Living {
+		o = G.Vote(p, Ja)
+	}
+	if _, ok := o.(Ok).Info.(VoteRegistered); ok {
+		t.Error("Expected the election to close once every living player voted")
+	}
+
+	// the rotation skips the dead
+	for i := 0; i < 14; i++ {
+		G.data.advancePresident()
+		if G.data.president == victim {
+			t.Fatal("The rotation reached a dead player")
+		}
+	}
+
+	// executing hitler ends the game
+	G = NewGameWithOptions(Options{Seed: 13})
+	for i := 0; i < 7; i++ {
+		G.AddPlayer()
+	}
+	G.Start()
+	G.data.state = specialExecution
+	for p, r := range G.data.roles {
+		if r == Hitler {
+			victim = int8(p)
+		}
+	}
+	G.data.chancellor = (victim + 1) % 7
+	if G.data.chancellor == G.data.president {
+		G.data.chancellor = (victim + 2) % 7
+	}
+	o = G.SpecialPower(G.data.president, Execution, victim)
+	if end, ok := o.(Ok).Info.(GameEnd); !ok || end.Why != LiberalExecutionWin {
+		t.Error("Expected a LiberalExecutionWin, got", o)
+	}
+}
+
+func TestTermLimits(t *testing.T) {
+	G := NewGameWithOptions(Options{Seed: 14})
+	for i := 0; i < 7; i++ {
+		G.AddPlayer()
+	}
+	G.Start()
+	p := G.data.president
+	if o := G.MakeChancellor(p, p); !rejected(o, Invalid{}) {
+		t.Error("Expected Invalid when the president nominates himself, got", o)
+	}
+	if s := G.StateFor(Spectator); len(s.Limited) != 0 {
+		t.Error("Expected nobody to be term-limited at the start, got", s.Limited)
+	}
+
+	// with more than five players alive, both members of the last government are term-limited
+	c := (p + 1) % 7
+	G.data.oldGov[0], G.data.oldGov[1] = (p+2)%7, c
+	if s := G.StateFor(Spectator); len(s.Limited) != 2 {
+		t.Error("Expected two term-limited players, got", s.Limited)
+	}
+	if o := G.MakeChancellor(p, (p+2)%7); !rejected(o, Invalid{}) {
+		t.Error("Expected Invalid when nominating the ex-president, got", o)
+	}
+	// with five players alive, only the ex-chancellor is
+	G.data.killed = append(G.data.killed, (p+3)%7, (p+4)%7)
+	if o := G.MakeChancellor(p, c); !rejected(o, Invalid{}) {
+		t.Error("Expected Invalid when nominating the ex-chancellor, got", o)
+	}
+	if _, ok := G.MakeChancellor(p, (p+2)%7).(Ok).Info.(ElectionStart); !ok {
+		t.Error("Expected the ex-president to be eligible with five players alive")
+	}
+
+	// a forced policy clears the term limits
+	G.data.killed = G.data.killed[:0]
+	G.data.eTracker = 2
+	for i := int8(0); i < 7; i++ {
+		G.Vote(i, Nein)
+	}
+	if s := G.StateFor(Spectator); len(s.Limited) != 0 || s.ElectionTracker != 0 {
+		t.Error("Expected the chaos to clear the term limits, got", s.Limited)
+	}
+
+	// after a special election the presidency goes back to the player after the one who called it
+	p = G.data.president
+	G.data.state = specialElection
+	special := (p + 3) % 7
+	o := G.SpecialPower(p, Election, special)
+	if G.data.president != special {
+		t.Fatal("The special election did not appoint", special)
+	}
+	if f := o.(Ok).Info.(SpecialPowerFeedback).Feedback; f != nil {
+		t.Error("The special election revealed the top of the deck:", f)
+	}
+	G.data.eTracker = 0
+	G.MakeChancellor(special, (special+1)%7)
+	for i := int8(0); i < 7; i++ {
+		G.Vote(i, Nein)
+	}
+	if G.data.president != (p+1)%7 {
+		t.Error("Expected the presidency to go back to", (p+1)%7, "got", G.data.president)
+	}
+}
+
+func TestInvestigation(t *testing.T) {
+	G := NewGameWithOptions(Options{Seed: 15})
+	for i := 0; i < 7; i++ {
+		G.AddPlayer()
+	}
+	G.Start()
+	var hitler int8
+	for i, r := range G.data.roles {
+		if r == Hitler {
+			hitler = int8(i)
+		}
+	}
+	G.data.president = (hitler + 1) % 7
+	G.data.nextPresident = (hitler + 2) % 7
+	G.data.state = specialInvestigate
+	p := G.data.president
+	if o := G.SpecialPower(p, Investigate, p); !rejected(o, Invalid{}) {
+		t.Error("Expected Invalid when the president investigates himself, got", o)
+	}
+
+	// investigating hitler only reveals that he is a fascist
+	o := G.SpecialPower(p, Investigate, hitler)
+	if f, ok := o.(Ok).Info.(SpecialPowerFeedback); !ok || f.Feedback != FascistMembership {
+		t.Fatal("Expected the fascist membership as feedback, got", o)
+	}
+	if s := G.StateFor(p); s.Parties[hitler] != FascistMembership || (G.data.roles[p] == LiberalParty && s.Roles[hitler] != UnknownRole) {
+		t.Error("Wrong view of the investigated player", s.Roles, s.Parties)
+	}
+	// everybody knows who was investigated, only the president knows the result
+	s := G.StateFor(Spectator)
+	if len(s.Investigated) != 1 || s.Investigated[0] != hitler || s.Parties[hitler] != UnknownParty {
+		t.Error("Wrong public view of the investigation", s.Investigated, s.Parties)
+	}
+
+	// nobody can be investigated twice
+	G.data.state = specialInvestigate
+	if o := G.SpecialPower(G.data.president, Investigate, hitler); !rejected(o, Invalid{}) {
+		t.Error("Expected Invalid when investigating a player twice, got", o)
+	}
+
+	// the investigation is skipped once everybody else was investigated
+	p = G.data.president
+	G.data.investigated, G.data.investigators = nil, nil
+	for i := int8(0); i < 7; i++ {
+		if i != p {
+			G.data.investigated = append(G.data.investigated, i)
+			G.data.investigators = append(G.data.investigators, p)
+		}
+	}
+	G.data.tracks[FascistPolicy] = 1
+	G.data.policyChoice = []Policy{FascistPolicy}
+	out := make(chan Output, 1)
+	G.data.enactPolicyActive(out)
+	if e, ok := (<-out).(Ok).Info.(PolicyEnaction); !ok || e.SpecialPower != Nothing {
+		t.Error("Expected the investigation to be skipped, got", e)
+	}
+	if G.data.state != chancellorCandidacy || G.data.president == p {
+		t.Error("Expected the presidency to move on after the skipped investigation, got", G.data.state)
+	}
+}
+
+func TestVeto(t *testing.T) {
+	G := NewGameWithOptions(Options{Seed: 16})
+	for i := 0; i < 5; i++ {
+		G.AddPlayer()
+	}
+	G.Start()
+	// elect a government that is not hitler's
+	p := G.data.president
+	c := (p + 1) % 5
+	if G.data.roles[c] == Hitler {
+		c = (p + 2) % 5
+	}
+	G.MakeChancellor(p, c)
+	for i := int8(0); i < 5; i++ {
+		G.Vote(i, Ja)
+	}
+	G.PolicyDiscard(p, 0)
+	if o := G.Veto(c); !rejected(o, Invalid{}) {
+		t.Error("Expected Invalid when vetoing before the veto is unlocked, got", o)
+	}
+	G.data.tracks[FascistPolicy] = G.data.rules.VetoUnlock
+	if o := G.Veto(p); !rejected(o, Unauthorized{}) {
+		t.Error("Expected Unauthorized when the president asks for a veto, got", o)
+	}
+
+	// the president sees the chancellor's hand
+	ch := G.SubscribeSpectator()
+	o := G.Veto(c)
+	v, ok := o.(Ok).Info.(VetoProposed)
+	if !ok || len(v.Hand) != 2 {
+		t.Fatal("Expected VetoProposed with two policies, got", o)
+	}
+	if e := <-ch; e.Output.(Ok).Info.(VetoProposed).Hand != nil {
+		t.Error("A spectator saw the vetoed policies")
+	}
+	G.Unsubscribe(ch)
+
+	// a rejected veto cannot be asked again
+	if _, ok := G.Vote(p, Nein).(Ok).Info.(VetoRejected); !ok {
+		t.Error("Expected VetoRejected")
+	}
+	if o := G.Veto(c); !rejected(o, Invalid{}) {
+		t.Error("Expected Invalid when asking for a veto twice, got", o)
+	}
+
+	// an accepted veto discards both policies and advances the election tracker
+	G.data.vetoDenied = false
+	discarded := len(G.data.deck.discarded)
+	G.Veto(c)
+	o = G.Vote(p, Ja)
+	if a, ok := o.(Ok).Info.(VetoAccepted); !ok || a.Forced || a.State.ElectionTracker != 1 {
+		t.Fatal("Expected VetoAccepted, got", o)
+	}
+	if len(G.data.deck.discarded) != discarded+2 {
+		t.Error("The vetoed policies were not discarded")
+	}
+	if G.data.president == p || G.data.tracks[FascistPolicy] != G.data.rules.VetoUnlock {
+		t.Error("The veto did not fail the government")
+	}
+}
+
+func TestRules(t *testing.T) {
+	if err := OfficialRules().Validate(); err != nil {
+		t.Fatal("The official rules are not valid:", err)
+	}
+	broken := []func(r *Rules){
+		func(r *Rules) { r.MinPlayers = 2 },
+		func(r *Rules) { r.MaxPlayers = 11 },
+		func(r *Rules) { r.Deck[FascistPolicy] = 5 },
+		func(r *Rules) { r.Deck = []int8{LiberalPolicy: 5, FascistPolicy: 6} },
+		func(r *Rules) { r.VetoUnlock = -1 },
+		func(r *Rules) { r.ChaosThreshold = 0 },
+		func(r *Rules) { r.Tables[0].Roles[FascistParty] = 5 },
+		func(r *Rules) { r.Tables[1].Powers = append(r.Tables[1].Powers, nil, nil) },
+		func(r *Rules) { r.Wins[2].Role = Communist },
+	}
+	for i, f := range broken {
+		r := OfficialRules()
+		f(&r)
+		if r.Validate() == nil {
+			t.Errorf("Broken rules %d passed validation", i)
+		}
+	}
+
+	// a short game for four players, with a shorter deck and tracks
+	r := Rules{
+		MinPlayers: 4, MaxPlayers: 4,
+		Roles: OfficialRules().Roles,
+		Deck:  []int8{LiberalPolicy: 4, FascistPolicy: 7},
+		Wins: []WinCondition{
+			{Kind: TrackFilled, Policy: LiberalPolicy, Count: 3, Ending: LiberalPolicyWin},
+			{Kind: TrackFilled, Policy: FascistPolicy, Count: 4, Ending: FascistPolicyWin},
+		},
+		VetoUnlock: 3, ChaosThreshold: 2,
+		Tables: []Table{{Players: 4, Roles: []int8{FascistParty: 1, Hitler: 1}, Powers: [][]SpecialPowers{FascistPolicy: {Nothing, Peek, Execution}}}},
+	}
+	G := NewGameWithOptions(Options{Seed: 17, Rules: r})
+	for i := 0; i < 4; i++ {
+		G.AddPlayer()
+	}
+	if o := G.AddPlayer(); !rejected(o, GameFull{}) {
+		t.Error("Expected GameFull past the maximum number of players, got", o)
+	}
+	s, ok := G.Start().(Ok).Info.(GameStart)
+	if !ok || s.Rules.Wins[0].Count != 3 || s.DeckSize != 11 {
+		t.Fatal("Expected GameStart to echo the rules")
+	}
+	// the second fascist policy grants a peek, both are taken from the pile
+	pile, taken := G.data.deck.pile[:0], 0
+	for _, p := range G.data.deck.pile {
+		if p == FascistPolicy && taken < 2 {
+			taken++
+			continue
+		}
+		pile = append(pile, p)
+	}
+	G.data.deck.pile = pile
+	G.data.deck.enact(FascistPolicy)
+	G.data.tracks[FascistPolicy] = 1
+	G.data.policyChoice = []Policy{FascistPolicy}
+	if p := G.data.enactPolicyInactive(); p != Peek {
+		t.Error("Expected Peek after the second fascist policy, got", p)
+	}
+	// two failed governments are enough to cause chaos
+	for k := 0; k < 2; k++ {
+		p := G.data.president
+		G.MakeChancellor(p, G.data.nextLiving(p))
+		for i := int8(0); i < 4; i++ {
+			G.Vote(i, Nein)
+		}
+	}
+	if G.data.eTracker != 0 || len(G.data.deck.enacted) != 3 {
+		t.Error("Expected a forced policy after two failed governments")
+	}
+
+	// the rules survive snapshots and replays
+	b, _ := G.Snapshot()
+	R, err := RestoreGame(b)
+	if err != nil || R.data.rules.Wins[1].Count != 4 {
+		t.Error("The rules were not restored", err)
+	}
+	L, err := Replay(G.Log())
+	if err != nil || L.data.rules.ChaosThreshold != 2 {
+		t.Error("The rules were not replayed", err)
+	}
+
+	// a government enacting a policy resets the election tracker
+	p := G.data.president
+	G.MakeChancellor(p, G.data.nextLiving(p))
+	for i := int8(0); i < 4; i++ {
+		G.Vote(i, Nein)
+	}
+	if G.data.eTracker != 1 {
+		t.Fatal("Expected the failed government to advance the election tracker, got", G.data.eTracker)
+	}
+	p = G.data.president
+	G.MakeChancellor(p, G.data.nextLiving(p))
+	for i := int8(0); i < 4; i++ {
+		G.Vote(i, Ja)
+	}
+	G.PolicyDiscard(p, 0)
+	G.PolicyDiscard(G.data.chancellor, 0)
+	if len(G.data.deck.enacted) != 4 || G.data.eTracker != 0 {
+		t.Error("Expected the enacted policy to reset the election tracker, got", G.data.eTracker)
+	}
+
+	// invalid house rules are reported, and the rules of a game are copied on the way in and out
+	if _, err := TryNewGame(Options{Rules: Rules{MinPlayers: 2, MaxPlayers: 4}}); err == nil {
+		t.Error("Expected invalid rules to be rejected")
+	}
+	H, err := TryNewGame(Options{Rules: r})
+	if err != nil {
+		t.Fatal("Expected valid rules to be accepted, got", err)
+	}
+	r.Deck[FascistPolicy] = 0
+	r.Tables[0].Powers[FascistPolicy][1] = Execution
+	H.Log().Rules.Wins[0].Count = 0
+	if l := H.Log().Rules; l.Deck[FascistPolicy] != 7 || l.Wins[0].Count != 3 || l.Tables[0].Powers[FascistPolicy][1] != Peek {
+		t.Error("The rules of the game were changed from outside:", l)
+	}
+}
+
+func TestExpansion(t *testing.T) {
+	// a third party, with its own policies, track and power
+	r := OfficialRules()
+	r.MinPlayers, r.MaxPlayers = 8, 8
+	r.Roles = append(r.Roles, RoleRules{Party: CommunistMembership, Knows: []Role{Communist}})
+	r.Deck = append(r.Deck, 8)
+	r.Wins = append(r.Wins, WinCondition{Kind: TrackFilled, Policy: CommunistPolicy, Count: 5, Ending: CommunistPolicyWin})
+	r.Tables = []Table{{
+		Players: 8,
+		Roles:   []int8{FascistParty: 1, Hitler: 1, Communist: 2},
+		Powers:  [][]SpecialPowers{FascistPolicy: {Nothing, Investigate}, CommunistPolicy: {Nothing, Investigate}},
+	}}
+	if err := r.Validate(); err != nil {
+		t.Fatal("The expansion rules are not valid:", err)
+	}
+	G := NewGameWithOptions(Options{Seed: 18, Rules: r})
+	for i := 0; i < 8; i++ {
+		G.AddPlayer()
+	}
+	G.Start()
+	var communists []int8
+	for p, role := range G.data.roles {
+		if role == Communist {
+			communists = append(communists, int8(p))
+		}
+	}
+	if len(communists) != 2 || G.data.deck.pile == nil || len(G.data.deck.pile) != 25 {
+		t.Fatal("Wrong setup of the expansion", G.data.roles, len(G.data.deck.pile))
+	}
+	// communists know each other
+	if s := G.StateFor(communists[0]); s.Roles[communists[1]] != Communist {
+		t.Error("The communists do not know each other", s.Roles)
+	}
+
+	// the second communist policy grants an investigation, which reveals the communist membership
+	G.data.tracks[CommunistPolicy] = 1
+	G.data.policyChoice = []Policy{CommunistPolicy}
+	G.data.enactPolicyActive(make(chan Output, 1))
+	if G.data.state != specialInvestigate || G.StateFor(Spectator).Tracks[CommunistPolicy] != 2 {
+		t.Fatal("Expected an investigation after the second communist policy")
+	}
+	p := G.data.president
+	target := communists[0]
+	if target == p {
+		target = communists[1]
+	}
+	if o := G.SpecialPower(p, Investigate, target); o.(Ok).Info.(SpecialPowerFeedback).Feedback != CommunistMembership {
+		t.Error("Expected the communist membership, got", o)
+	}
+
+	// filling the communist track ends the game
+	G.data.tracks[CommunistPolicy] = 4
+	G.data.policyChoice = []Policy{CommunistPolicy}
+	out := make(chan Output, 1)
+	G.data.enactPolicyActive(out)
+	if end, ok := (<-out).(Ok).Info.(GameEnd); !ok || end.Why != CommunistPolicyWin {
+		t.Error("Expected a CommunistPolicyWin, got", end)
+	}
+
+	// hitler only wins by election once three fascist policies are enacted
+	H := NewGameWithOptions(Options{Seed: 18})
+	for i := 0; i < 5; i++ {
+		H.AddPlayer()
+	}
+	H.Start()
+	for i, role := range H.data.roles {
+		if role == Hitler && int8(i) != H.data.president {
+			H.MakeChancellor(H.data.president, int8(i))
+			break
+		}
+	}
+	if H.data.state == governmentElection {
+		for i := int8(0); i < 5; i++ {
+			o := H.Vote(i, Ja)
+			if _, ok := o.(Ok).Info.(GameEnd); ok {
+				t.Error("Hitler won outside of the hitler zone")
+			}
+		}
+	}
+}
+
+func TestLargeTables(t *testing.T) {
+	r := LargeTableRules()
+	if err := r.Validate(); err != nil {
+		t.Fatal("The large table rules are not valid:", err)
+	}
+	r.Tables[8].Deck = []int8{8, 4}
+	if r.Validate() == nil {
+		t.Error("A deck that cannot fill the fascist track passed validation")
+	}
+	r.Tables[8].Deck = []int8{LiberalPolicy: 5, FascistPolicy: 6}
+	if r.Validate() == nil {
+		t.Error("A deck too small to draw from once the tracks fill up passed validation")
+	}
+
+	G := NewGameWithOptions(Options{Seed: 19, Rules: LargeTableRules()})
+	for i := 0; i < 16; i++ {
+		if _, ok := G.AddPlayer().(Ok); !ok {
+			t.Fatal("Could not add player", i)
+		}
+	}
+	if o := G.AddPlayer(); !rejected(o, GameFull{}) {
+		t.Error("Expected GameFull with 17 players, got", o)
+	}
+	G.Leave(0)
+	G.Leave(0)
+	s, ok := G.Start().(Ok).Info.(GameStart)
+	if !ok || s.DeckSize != 22 {
+		t.Fatal("Expected a game of 14 players with a deck of 22 policies")
+	}
+	fascists := 0
+	for _, role := range G.data.roles {
+		if role == FascistParty {
+			fascists++
+		}
+	}
+	if fascists != 5 {
+		t.Error("Expected 5 fascists at 14 players, got", fascists)
+	}
+	// the snapshots of large games can be restored
+	b, _ := G.Snapshot()
+	if _, err := RestoreGame(b); err != nil {
+		t.Error("Could not restore a large game:", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	G := NewGameWithOptions(Options{Seed: 20})
+	for i := 0; i < 4; i++ {
+		G.AddPlayer()
+	}
+	// starting without enough players gets an answer
+	o := G.Start()
+	if !rejected(o, Invalid{Reason{Code: NotEnoughPlayers}}) {
+		t.Fatal("Expected NotEnoughPlayers, got", o)
+	}
+	var err error = o.(Error)
+	var invalid Invalid
+	if !errors.As(err, &invalid) || invalid.Code != NotEnoughPlayers || invalid.Message == "" {
+		t.Error("Expected to get the Invalid error out of", err)
+	}
+	if !strings.Contains(err.Error(), "more players") {
+		t.Error("Wrong message:", err)
+	}
+	if errors.Is(err, Invalid{Reason{Code: TermLimited}}) || errors.Is(err, WrongPhase{}) {
+		t.Error("The error matched the wrong reason")
+	}
+
+	G.AddPlayer()
+	G.Start()
+	p := G.data.president
+	o = G.MakeChancellor(p, 7)
+	if !rejected(o, Invalid{Reason{Code: UnknownSeat, Field: "Proposal"}}) {
+		t.Error("Expected UnknownSeat on Proposal, got", o)
+	}
+	if o := G.MakeChancellor((p+1)%5, p); !rejected(o, Unauthorized{Reason{Code: NotPresident}}) {
+		t.Error("Expected NotPresident, got", o)
+	}
+	c := (p + 1) % 5
+	G.MakeChancellor(p, c)
+	G.Vote(0, Ja)
+	if o := G.Vote(0, Ja); !rejected(o, Unauthorized{Reason{Code: AlreadyVoted}}) {
+		t.Error("Expected AlreadyVoted, got", o)
+	}
+	if o := G.Vote(1, NoVote); !rejected(o, Invalid{Reason{Code: InvalidVote, Field: "Vote"}}) {
+		t.Error("Expected InvalidVote, got", o)
+	}
+	for i := int8(1); i < 5; i++ {
+		G.Vote(i, Ja)
+	}
+	if G.data.state == gameEnd {
+		return
+	}
+	// discarding a card out of the hand gets an answer
+	if o := G.PolicyDiscard(p, 3); !rejected(o, Invalid{Reason{Code: OutOfRange, Field: "Selection"}}) {
+		t.Error("Expected OutOfRange, got", o)
+	}
+	G.PolicyDiscard(p, 0)
+	if o := G.PolicyDiscard(c, 2); !rejected(o, Invalid{Reason{Code: OutOfRange}}) {
+		t.Error("Expected OutOfRange, got", o)
+	}
+	if o := G.Veto(c); !rejected(o, Invalid{Reason{Code: VetoLocked}}) {
+		t.Error("Expected VetoLocked, got", o)
+	}
+	if o, _ := G.Do(context.Background(), Command{Kind: TimeoutCommand}); !rejected(o, Unauthorized{Reason{Code: Reserved}}) {
+		t.Error("Expected Reserved, got", o)
+	}
+}
+
+func TestResults(t *testing.T) {
+	G := NewGameWithOptions(Options{Seed: 21})
+	ctx := context.Background()
+	for i := 0; i < 7; i++ {
+		if r, err := G.Apply(ctx, Command{Kind: AddPlayerCommand}); err != nil || r != PlayerRegistered(i) {
+			t.Fatal("Expected PlayerRegistered, got", r, err)
+		}
+	}
+	r, err := G.Apply(ctx, Command{Kind: StartCommand, Caller: NotSet})
+	if start, ok := r.(GameStart); err != nil || !ok || start.Kind() != GameStartResult || len(start.Players) != 7 {
+		t.Fatal("Expected GameStart, got", r, err)
+	}
+
+	// play the game through, always picking the first legal choice
+	seen := map[ResultKind]bool{}
+	for steps := 0; r.Kind() != GameEndResult; steps++ {
+		if steps > 1000 {
+			t.Fatal("The game did not end")
+		}
+		g := G.data
+		p := g.president
+		target := NotSet
+		for _, s := range g.livingPlayers() {
+			if g.powerTargetCode(s) == NoCode && !search(g.investigated, s) && (g.state != chancellorCandidacy || g.eligible(s)) {
+				target = s
+				break
+			}
+		}
+		var c Command
+		switch g.state {
+		case chancellorCandidacy:
+			c = Command{Kind: MakeChancellorCommand, Caller: p, Proposal: target}
+		case governmentElection:
+			for _, s := range g.livingPlayers() {
+				if g.votes[s] == NoVote {
+					c = Command{Kind: VoteCommand, Caller: s, Vote: Ja}
+					if steps%3 == 0 {
+						c.Vote = Nein
+					}
+					break
+				}
+			}
+		case presidentLegislation:
+			c = Command{Kind: PolicyDiscardCommand, Caller: p}
+		case chancellorLegislation:
+			c = Command{Kind: PolicyDiscardCommand, Caller: g.chancellor, Selection: int8(steps % 2)}
+		case specialPeek:
+			c = Command{Kind: SpecialPowerCommand, Caller: p, Power: Peek}
+		case specialInvestigate:
+			c = Command{Kind: SpecialPowerCommand, Caller: p, Power: Investigate, Selection: target}
+		case specialElection:
+			c = Command{Kind: SpecialPowerCommand, Caller: p, Power: Election, Selection: target}
+		case specialExecution:
+			c = Command{Kind: SpecialPowerCommand, Caller: p, Power: Execution, Selection: target}
+		default:
+			t.Fatal("Unexpected state", g.state)
+		}
+		if r, err = G.Apply(ctx, c); err != nil {
+			t.Fatal("Command", c, "was rejected:", err)
+		}
+		seen[r.Kind()] = true
+	}
+	for _, k := range []ResultKind{VoteRegisteredResult, LegislationPresidentResult, LegislationChancellorResult, PolicyEnactionResult, GameEndResult} {
+		if !seen[k] {
+			t.Error("Expected a result of kind", k)
+		}
+	}
+
+	// every logged output unpacks to a Result of its own kind
+	for _, e := range G.Log().Entries {
+		r, err := Unpack(e.Output)
+		if err != nil || !reflect.DeepEqual(r, e.Output.(Ok).Info) {
+			t.Error("Could not unpack", e.Output, err)
+		}
+	}
+
+	// rejected commands unpack to their Error
+	_, err = G.Apply(ctx, Command{Kind: VoteCommand, Caller: 0, Vote: Ja})
+	if !errors.Is(err, WrongPhase{}) {
+		t.Error("Expected WrongPhase, got", err)
+	}
+	if _, err := Unpack(nil); err == nil {
+		t.Error("Unpacking nil should fail")
+	}
+}
+
+func TestLegalActions(t *testing.T) {
+	G := NewGameWithOptions(Options{Seed: 22})
+	if a := G.LegalActions(Spectator); len(a) != 1 || a[0].Kind != AddPlayerCommand {
+		t.Error("Expected the spectators to be able to join, got", a)
+	}
+	for i := 0; i < 6; i++ {
+		G.AddPlayer()
+	}
+	if a := G.LegalActions(0); len(a) != 4 || a[0].Kind != StartCommand || !reflect.DeepEqual(a[1].Choices, []int8{1, 2, 3, 4, 5}) {
+		t.Error("Expected the host to start, kick, leave and get ready, got", a)
+	}
+	if a := G.LegalActions(3); len(a) != 2 {
+		t.Error("Expected a guest to leave and get ready, got", a)
+	}
+	if G.Phase() != WaitingPlayersPhase || G.Phase().String() != "waitingPlayers" {
+		t.Error("Expected WaitingPlayersPhase, got", G.Phase())
+	}
+	G.Start()
+
+	rng := rand.New(rand.NewSource(22))
+	ctx := context.Background()
+	for steps := 0; G.Phase() != GameEndPhase; steps++ {
+		if steps > 1000 {
+			t.Fatal("The game did not end")
+		}
+		if s := G.StateFor(Spectator); s.Phase != G.Phase() {
+			t.Error("The state and the game disagree on the phase")
+		}
+		var cmds []Command
+		for p := int8(0); p < 6; p++ {
+			for _, a := range G.LegalActions(p) {
+				c := Command{Kind: a.Kind, Caller: p, Power: a.Power}
+				if len(a.Votes) > 0 {
+					c.Vote = a.Votes[rng.Intn(len(a.Votes))]
+				}
+				if len(a.Choices) > 0 {
+					c.Selection = a.Choices[rng.Intn(len(a.Choices))]
+					c.Proposal = c.Selection
+				}
+				cmds = append(cmds, c)
+				// the seats left out of the choices are refused
+				if a.Kind == MakeChancellorCommand || a.Kind == SpecialPowerCommand && a.Power != Peek {
+					for s := int8(0); s < 6; s++ {
+						if !search(a.Choices, s) {
+							bad := c
+							bad.Selection, bad.Proposal = s, s
+							if o, _ := G.Do(ctx, bad); !rejected(o, Invalid{}) {
+								t.Error("Expected", s, "to be refused by", a, "got", o)
+							}
+						}
+					}
+				}
+			}
+		}
+		if len(cmds) == 0 {
+			t.Fatal("Nobody can act in phase", G.Phase())
+		}
+		c := cmds[rng.Intn(len(cmds))]
+		if _, err := G.Apply(ctx, c); err != nil {
+			t.Fatal("Legal command", c, "was rejected:", err)
+		}
+	}
+	for p := int8(0); p < 6; p++ {
+		if a := G.LegalActions(p); a != nil {
+			t.Error("Expected no actions after the end, got", a)
+		}
+	}
+	G.Close()
+	if G.Phase() != GameEndPhase || G.LegalActions(0) != nil {
+		t.Error("Expected a closed game to be over")
+	}
+}
+
+func TestBots(t *testing.T) {
+	play := func(players int8, seed int64) (GameEnd, Log) {
+		G := NewGameWithOptions(Options{Seed: seed})
+		defer G.Close()
+		bots := map[int8]Bot{}
+		for p := int8(0); p < players; p++ {
+			G.AddPlayer()
+			bots[p] = NewRandomBot(seed + int64(p))
+		}
+		G.Start()
+		end, err := NewDriver(&G, bots).Run(context.Background())
+		if err != nil {
+			t.Fatal("The bots could not finish the game:", err)
+		}
+		return end, G.Log()
+	}
+	for players := int8(5); players <= 10; players++ {
+		for seed := int64(0); seed < 10; seed++ {
+			if end, _ := play(players, seed); end.Why == StillRunning {
+				t.Error("Expected the game to end, got", end)
+			}
+		}
+	}
+	// seeded games with seeded bots are reproducible
+	_, a := play(7, 23)
+	_, b := play(7, 23)
+	if len(a.Entries) != len(b.Entries) {
+		t.Fatal("The same seeds produced different games")
+	}
+	for i := range a.Entries {
+		if a.Entries[i].Command != b.Entries[i].Command {
+			t.Fatal("The same seeds produced different games")
+		}
+	}
+
+	// bots wait for the players they share the table with
+	G := NewGameWithOptions(Options{Seed: 23})
+	bots := map[int8]Bot{}
+	for p := int8(0); p < 5; p++ {
+		G.AddPlayer()
+		if p > 0 {
+			bots[p] = NewRandomBot(int64(p))
+		}
+	}
+	done := make(chan error)
+	go func() {
+		_, err := NewDriver(&G, bots).Run(context.Background())
+		done <- err
+	}()
+	// the bots get ready in the lobby
+	for deadline := time.Now().Add(time.Second); ; {
+		if r := G.StateFor(0).Ready; r[1] && r[2] && r[3] && r[4] {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("The bots did not get ready")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	G.Start()
+	human := NewRandomBot(0)
+	for G.Phase() != GameEndPhase {
+		if a := G.LegalActions(0); len(a) > 0 {
+			G.Apply(context.Background(), human.Act(Observation{Seat: 0, Actions: a}))
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+	if err := <-done; err != nil {
+		t.Error("The bots could not finish the game:", err)
+	}
+
+	// a closed game stops the driver
+	G = NewGame()
+	for p := int8(0); p < 5; p++ {
+		G.AddPlayer()
+	}
+	go func() {
+		_, err := NewDriver(&G, map[int8]Bot{1: NewRandomBot(1)}).Run(context.Background())
+		done <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+	G.Close()
+	if err := <-done; !errors.Is(err, GameClosed{}) {
+		t.Error("Expected GameClosed, got", err)
+	}
+
+	// bots cannot play seats nobody has taken
+	G = NewGame()
+	G.AddPlayer()
+	if _, err := NewDriver(&G, map[int8]Bot{3: NewRandomBot(3)}).Run(context.Background()); !errors.Is(err, Invalid{}) {
+		t.Error("Expected Invalid, got", err)
+	}
+}
+
+func TestStrategyBots(t *testing.T) {
+	// liberalWins plays seeded games of 7 bots, returning how many the liberals won
+	liberalWins := func(liberal, fascist func(seed int64) Bot) int {
+		wins := 0
+		for seed := int64(0); seed < 50; seed++ {
+			G := NewGameWithOptions(Options{Seed: seed})
+			for p := 0; p < 7; p++ {
+				G.AddPlayer()
+			}
+			G.Start()
+			bots := map[int8]Bot{}
+			for p := int8(0); p < 7; p++ {
+				if G.data.roles[p] == LiberalParty {
+					bots[p] = liberal(seed*10 + int64(p))
+				} else {
+					bots[p] = fascist(seed*10 + int64(p))
+				}
+			}
+			end, err := NewDriver(&G, bots).Run(context.Background())
+			if err != nil {
+				t.Fatal("The bots could not finish the game:", err)
+			}
+			if end.Why == LiberalPolicyWin || end.Why == LiberalExecutionWin {
+				wins++
+			}
+			G.Close()
+		}
+		return wins
+	}
+	random := func(seed int64) Bot { return NewRandomBot(seed) }
+	level := func(l Difficulty) func(int64) Bot {
+		return func(seed int64) Bot { return NewStrategyBot(seed, l) }
+	}
+
+	base := liberalWins(random, random)
+	for _, l := range []Difficulty{Easy, Normal, Hard} {
+		if w := liberalWins(level(l), random); w <= base {
+			t.Error("Liberal bots at level", l, "won", w, "games, random ones won", base)
+		}
+		if w := liberalWins(random, level(l)); w >= base {
+			t.Error("Fascist bots at level", l, "lost", w, "games, random ones lost", base)
+		}
+	}
+	if easy, hard := liberalWins(level(Easy), level(Hard)), liberalWins(level(Hard), level(Easy)); easy >= hard {
+		t.Error("Hard liberals won", hard, "games against easy fascists, easy liberals won", easy, "against hard fascists")
+	}
+
+	// fascists lie about the liberal policies they discarded, liberals do not
+	G := NewGameWithOptions(Options{Seed: 24})
+	for p := 0; p < 5; p++ {
+		G.AddPlayer()
+	}
+	G.Start()
+	hand := []Policy{LiberalPolicy, FascistPolicy, FascistPolicy}
+	for p := int8(0); p < 5; p++ {
+		o := Observation{Seat: p, State: G.StateFor(p), Rules: OfficialRules(), Hand: hand}
+		claim := NewStrategyBot(0, Normal).Claim(o, FascistPolicy)
+		liberal := G.data.roles[p] == LiberalParty
+		if liberal != reflect.DeepEqual(claim, hand) {
+			t.Error("Seat", p, "claimed", claim, "holding", hand)
+		}
+		if claim := NewStrategyBot(0, Easy).Claim(o, FascistPolicy); !reflect.DeepEqual(claim, hand) {
+			t.Error("Easy bots should not lie, seat", p, "claimed", claim)
+		}
+	}
+
+	// the policies a party pushes are the ones whose track wins it the game
+	swapped := OfficialRules()
+	swapped.Wins[0].Ending, swapped.Wins[1].Ending = FascistPolicyWin, LiberalPolicyWin
+	o := Observation{
+		Seat:  0,
+		State: GameState{Parties: []Party{FascistMembership}, Roles: []Role{FascistParty}, Tracks: []int8{0, 0}},
+		Rules: swapped,
+	}
+	if p := NewStrategyBot(0, Hard).pushes(o); p != LiberalPolicy {
+		t.Error("Expected the fascists to push the policies of the track they win with, got", p)
+	}
+}
@@ -0,0 +1,125 @@
+package SecretGopher
+
+import "context"
+
+// Action is a command a player can send in the current phase, along with the values its parameters can take.
+// Sending the command with any of those values is accepted by the game
+type Action struct {
+	Kind    CommandKind
+	Power   SpecialPowers // Power is the power of a SpecialPowerCommand
+	Choices []int8        // Choices are the valid Proposal of a MakeChancellorCommand, or the valid Selection of the other commands
+	Votes   []Vote        // Votes are the valid Vote of a VoteCommand
+}
+
+// Phase returns the phase the game is in.
+// A closed game is in GameEndPhase
+func (g *Game) Phase() Phase {
+	o, _ := g.send(context.Background(), viewSummary{})
+	if s, ok := o.(summary); ok {
+		return Phase(s.state)
+	}
+	return GameEndPhase
+}
+
+// LegalActions returns the commands player can send right now.
+// Passing Spectator returns the commands of whoever does not sit at the table, which is at most an AddPlayerCommand.
+// A closed game, or a player who has nothing to do, returns no actions
+func (g *Game) LegalActions(player int8) []Action {
+	o, _ := g.send(context.Background(), viewActions{Viewer: player})
+	a, _ := o.([]Action)
+	return a
+}
+
+// legalActions returns the commands p can send in the current state of the game
+func (g *gameData) legalActions(p int8) []Action {
+	var a []Action
+	if g.state == waitingPlayers {
+		if p < 0 || p >= g.players {
+			if g.players < g.rules.MaxPlayers {
+				a = append(a, Action{Kind: AddPlayerCommand})
+			}
+			return a
+		}
+		if p == g.host {
+			if g.players >= g.rules.MinPlayers {
+				a = append(a, Action{Kind: StartCommand})
+			}
+			var kick []int8
+			for t := int8(0); t < g.players; t++ {
+				if t != p {
+					kick = append(kick, t)
+				}
+			}
+			if kick != nil {
+				a = append(a, Action{Kind: KickCommand, Choices: kick})
+			}
+		}
+		return append(a, Action{Kind: LeaveCommand}, Action{Kind: ReadyCommand})
+	}
+	if !g.alive(p) {
+		return nil // the dead and the spectators only watch
+	}
+
+	switch g.state {
+	case chancellorCandidacy:
+		if p == g.president {
+			a = append(a, Action{Kind: MakeChancellorCommand, Choices: g.choices(g.nomineeCode)})
+		}
+	case governmentElection:
+		if g.voterCode(p) == NoCode {
+			a = append(a, Action{Kind: VoteCommand, Votes: []Vote{Ja, Nein}})
+		}
+	case presidentLegislation:
+		if p == g.president {
+			a = append(a, Action{Kind: PolicyDiscardCommand, Choices: g.hand()})
+		}
+	case chancellorLegislation:
+		if p == g.chancellor {
+			a = append(a, Action{Kind: PolicyDiscardCommand, Choices: g.hand()})
+			if g.vetoCode() == NoCode {
+				a = append(a, Action{Kind: VetoCommand})
+			}
+		}
+	case specialPeek:
+		if p == g.president {
+			a = append(a, Action{Kind: SpecialPowerCommand, Power: Peek})
+		}
+	case specialInvestigate:
+		if p == g.president {
+			a = append(a, Action{Kind: SpecialPowerCommand, Power: Investigate, Choices: g.choices(g.investigateCode)})
+		}
+	case specialElection:
+		if p == g.president {
+			a = append(a, Action{Kind: SpecialPowerCommand, Power: Election, Choices: g.choices(g.powerTargetCode)})
+		}
+	case specialExecution:
+		if p == g.president {
+			a = append(a, Action{Kind: SpecialPowerCommand, Power: Execution, Choices: g.choices(g.powerTargetCode)})
+		}
+	case vetoPresident:
+		if p == g.president {
+			a = append(a, Action{Kind: VoteCommand, Votes: []Vote{Ja, Nein}})
+		}
+	}
+	return a
+}
+
+// choices returns the seats for which code has no objection
+func (g *gameData) choices(code func(int8) Code) []int8 {
+	var c []int8
+	for p := int8(0); p < g.players; p++ {
+		if code(p) == NoCode {
+			c = append(c, p)
+		}
+	}
+	return c
+}
+
+// hand returns the indices of the policies in the hand of the government
+func (g *gameData) hand() []int8 {
+	h := make([]int8, len(g.policyChoice))
+	for i := range h {
+		h[i] = int8(i)
+	}
+	return h
+}
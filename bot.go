@@ -0,0 +1,305 @@
+package SecretGopher
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+)
+
+// Observation is what a bot knows when it is its turn to act
+type Observation struct {
+	Seat    int8      // Seat is the seat of the bot
+	State   GameState // State is the state of the game as seen by the bot
+	Rules   Rules     // Rules are the rules of the game
+	Actions []Action  // Actions are the commands the bot can send, there is always at least one
+	Hand    []Policy  // Hand is the hand of the bot if it has to discard a policy, or the hand of the chancellor if it has to answer a veto
+	Events  []Event   // Events are the changes of the game seen by the bot since it last acted
+	Claims  []Claim   // Claims are the claims made by the bots at the table so far
+}
+
+// Bot is a strategy playing a seat of a game.
+// Act is called every time the bot has to act, and returns the command the bot sends.
+// The Caller of the command is always set to the seat of the bot
+type Bot interface {
+	Act(o Observation) Command
+}
+
+// Claimer is a Bot that talks about the hands it held.
+// Claim is called after every legislative session the bot took part in that enacted a policy, with the hand the bot
+// held in Observation.Hand and the policy the session enacted, and returns the hand the bot says it held,
+// or nil to stay silent
+type Claimer interface {
+	Bot
+	Claim(o Observation, enacted Policy) []Policy
+}
+
+// Claim is what a player said about the hand he held in a legislative session
+type Claim struct {
+	Seat       int8     // Seat is the player making the claim
+	President  int8     // President is the president of the session
+	Chancellor int8     // Chancellor is the chancellor of the session
+	Enacted    Policy   // Enacted is the policy the session enacted
+	Hand       []Policy // Hand is the claimed hand: the three policies drawn by a president, or the two received by a chancellor
+}
+
+// Command returns the command sending the action on behalf of caller, with choice as its Proposal or Selection
+func (a Action) Command(caller, choice int8) Command {
+	c := Command{Kind: a.Kind, Caller: caller, Power: a.Power, Selection: choice}
+	if a.Kind == MakeChancellorCommand {
+		c.Proposal, c.Selection = choice, 0
+	}
+	return c
+}
+
+// Driver plays some seats of a game with bots, while the other seats are left to their players
+type Driver struct {
+	game   *Game
+	bots   map[int8]Bot
+	rules  Rules
+	seats  map[int8]*seat
+	claims []Claim
+	gov    []int8 // gov is the government of the ongoing legislative session, if any
+	seen   int    // seen is the number of public events observed so far
+}
+
+// seat is the view of the game of a seat played by a bot
+type seat struct {
+	bot    Bot
+	ch     <-chan Event
+	events []Event
+	hand   []Policy // hand is the last hand the seat held
+	veto   []Policy // veto is the hand of the last veto the seat saw
+}
+
+// NewDriver plugs the bots into the seats of the game they are mapped to.
+// The seats must be taken before Run is called
+func NewDriver(g *Game, bots map[int8]Bot) *Driver {
+	return &Driver{game: g, bots: bots}
+}
+
+// Run plays the seats of the bots until the game ends, and returns how it ended.
+// While the game is in the lobby the bots only tell they are ready; once it starts they act as soon as it is
+// their turn, and wait for the other players otherwise.
+// Run gives up when ctx is done, when the game is closed, or when a bot sends a command the game rejects.
+// It fails right away if a bot is mapped to a seat nobody has taken
+func (d *Driver) Run(ctx context.Context) (GameEnd, error) {
+	players := int8(len(d.game.StateFor(Spectator).Players))
+	for p := range d.bots {
+		if p < 0 || p >= players {
+			return GameEnd{}, Error{Err: Invalid{because(UnknownSeat, "")}}
+		}
+	}
+	order := make([]int8, 0, len(d.bots)) // seats are visited in order, so that seeded games are reproducible
+	d.seats = make(map[int8]*seat, len(d.bots))
+	for p, b := range d.bots {
+		order = append(order, p)
+		d.seats[p] = &seat{bot: b, ch: d.game.Subscribe(p)}
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+	d.rules = d.game.Log().Rules
+	watch := d.game.SubscribeSpectator()
+	defer func() {
+		d.game.Unsubscribe(watch)
+		for _, s := range d.seats {
+			d.game.Unsubscribe(s.ch)
+		}
+	}()
+
+	for d.game.Phase() != GameEndPhase {
+		acted, seen := false, d.seen
+		for _, p := range order {
+			if !d.listen(watch) {
+				watch = d.game.SubscribeSpectator() // the watch fell behind
+			}
+			ok, err := d.act(ctx, p, d.seats[p])
+			if err != nil {
+				return GameEnd{}, err
+			}
+			acted = acted || ok
+		}
+		if acted || d.seen != seen {
+			continue // the events observed in the meantime may let the seats already visited act
+		}
+		// nobody can act: wait for somebody else to
+		select {
+		case e, ok := <-watch:
+			if ok {
+				d.observe(e)
+			} else if d.game.Phase() != GameEndPhase {
+				watch = d.game.SubscribeSpectator() // the watch fell behind
+			}
+		case <-ctx.Done():
+			return GameEnd{}, ctx.Err()
+		}
+	}
+	l := d.game.Log().Entries
+	if len(l) > 0 {
+		if end, ok := ending(l[len(l)-1].Output); ok {
+			return end, nil
+		}
+	}
+	return GameEnd{}, Error{Err: GameClosed{because(Closed, "")}}
+}
+
+// ending returns the GameEnd reported by o, if any
+func ending(o Output) (GameEnd, bool) {
+	switch r, _ := Unpack(o); r := r.(type) {
+	case GameEnd:
+		return r, true
+	case TimeoutApplied:
+		return ending(r.Result)
+	}
+	return GameEnd{}, false
+}
+
+// listen collects the public events of the game received by watch.
+// It returns false if watch was closed
+func (d *Driver) listen(watch <-chan Event) bool {
+	for {
+		select {
+		case e, ok := <-watch:
+			if !ok {
+				return false
+			}
+			d.observe(e)
+		default:
+			return true
+		}
+	}
+}
+
+// observe follows the legislative sessions, collecting the claims of the bots in the government once a session
+// enacts a policy
+func (d *Driver) observe(e Event) {
+	d.seen++
+	r, _ := Unpack(e.Output)
+	if t, ok := r.(TimeoutApplied); ok {
+		r, _ = t.Outcome()
+	}
+	switch r := r.(type) {
+	case LegislationPresident:
+		d.gov = []int8{r.State.President, r.State.Chancellor}
+	case PolicyEnaction:
+		for _, p := range d.gov {
+			d.claim(p, d.gov, r)
+		}
+		d.gov = nil
+	case NextPresident, VetoAccepted, GameEnd:
+		d.gov = nil
+	}
+}
+
+// claim asks the bot in seat p, if any, to claim the hand it held in the session of gov
+func (d *Driver) claim(p int8, gov []int8, r PolicyEnaction) {
+	s, ok := d.seats[p]
+	if !ok {
+		return
+	}
+	c, ok := s.bot.(Claimer)
+	if !ok {
+		return
+	}
+	if !s.drain() {
+		s.ch = d.game.Subscribe(p) // the bot fell behind
+	}
+	hand := c.Claim(Observation{Seat: p, State: d.game.StateFor(p), Rules: d.rules, Hand: s.hand, Claims: d.claims}, r.Enacted)
+	if hand != nil {
+		d.claims = append(d.claims, Claim{Seat: p, President: gov[0], Chancellor: gov[1], Enacted: r.Enacted, Hand: hand})
+	}
+}
+
+// act asks the bot in seat p to act, if it can. The boolean tells if the bot acted
+func (d *Driver) act(ctx context.Context, p int8, s *seat) (bool, error) {
+	if !s.drain() {
+		s.ch = d.game.Subscribe(p) // the bot fell behind
+	}
+	actions := d.game.LegalActions(p)
+	if len(actions) == 0 {
+		return false, nil
+	}
+	if d.game.Phase() == WaitingPlayersPhase {
+		if r := d.game.StateFor(p).Ready; p >= int8(len(r)) || r[p] {
+			return false, nil // the seat is ready, or was left
+		}
+		_, err := d.game.Apply(ctx, Command{Kind: ReadyCommand, Caller: p, Ready: true})
+		return err == nil, err
+	}
+	o := Observation{Seat: p, State: d.game.StateFor(p), Rules: d.rules, Actions: actions, Events: s.events, Claims: d.claims}
+	switch {
+	case o.State.Phase == VetoPresidentPhase:
+		o.Hand = s.veto
+	case actions[0].Kind == PolicyDiscardCommand:
+		o.Hand = s.hand
+	}
+	s.events = nil
+	c := s.bot.Act(o)
+	c.Caller, c.CallerID = p, ""
+	if _, err := d.game.Apply(ctx, c); err != nil {
+		if errors.Is(err, WrongPhase{}) {
+			return false, nil // somebody else moved the game on in the meantime
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// drain collects the events the seat received, keeping track of its hand.
+// It returns false if the subscription of the seat was closed
+func (s *seat) drain() bool {
+	for {
+		select {
+		case e, ok := <-s.ch:
+			if !ok {
+				return false
+			}
+			s.events = append(s.events, e)
+			s.track(e.Output)
+		default:
+			return true
+		}
+	}
+}
+
+// track keeps the hand of the seat up to date with output o
+func (s *seat) track(o Output) {
+	// the hands the seat is not entitled to see are redacted
+	switch r, _ := Unpack(o); r := r.(type) {
+	case LegislationPresident:
+		if r.Hand != nil {
+			s.hand = r.Hand
+		}
+	case LegislationChancellor:
+		if r.Hand != nil {
+			s.hand = r.Hand
+		}
+	case VetoProposed:
+		s.veto = r.Hand
+	case TimeoutApplied:
+		s.track(r.Result)
+	}
+}
+
+// RandomBot plays a random legal command every time it acts
+type RandomBot struct {
+	rng *rand.Rand
+}
+
+// NewRandomBot creates a RandomBot drawing its choices from a source seeded with seed
+func NewRandomBot(seed int64) *RandomBot {
+	return &RandomBot{rng: rand.New(rand.NewSource(seed))}
+}
+
+// Act picks one of the legal actions, and one of its choices, at random
+func (b *RandomBot) Act(o Observation) Command {
+	a := o.Actions[b.rng.Intn(len(o.Actions))]
+	choice := int8(0)
+	if len(a.Choices) > 0 {
+		choice = a.Choices[b.rng.Intn(len(a.Choices))]
+	}
+	c := a.Command(o.Seat, choice)
+	if len(a.Votes) > 0 {
+		c.Vote = a.Votes[b.rng.Intn(len(a.Votes))]
+	}
+	return c
+}
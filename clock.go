@@ -0,0 +1,29 @@
+package SecretGopher
+
+import "time"
+
+// Clock is the source of time used to measure the deadlines of a game.
+// It can be replaced to control the flow of time, i.e. in tests
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine once d has elapsed
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a call scheduled by a Clock
+type Timer interface {
+	// Stop prevents the call from happening. It returns false if the call already happened or was stopped
+	Stop() bool
+}
+
+// systemClock is the Clock backed by the time package
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
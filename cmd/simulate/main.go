@@ -0,0 +1,82 @@
+// Command simulate plays a batch of games between bots and prints how they went.
+//
+//	simulate -games 10000 -players 7 -liberals hard -fascists normal
+//
+// The bots are one of random, easy, normal or hard. The rules are either official or large, and can be tuned with
+// -veto and -chaos
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	SG "github.com/nylone/SecretGopher"
+	"github.com/nylone/SecretGopher/simulation"
+)
+
+func main() {
+	games := flag.Int("games", 1000, "number of games to play")
+	players := flag.Int("players", 7, "number of players at every table")
+	seed := flag.Int64("seed", 1, "seed of the first game")
+	workers := flag.Int("workers", 0, "number of games played at once, 0 for one per CPU")
+	liberals := flag.String("liberals", "random", "bots playing the liberals")
+	fascists := flag.String("fascists", "random", "bots playing the other parties")
+	rules := flag.String("rules", "official", "rules of the games: official or large")
+	veto := flag.Int("veto", -1, "fascist policies unlocking the veto, 0 to disable it, -1 to keep the rules'")
+	chaos := flag.Int("chaos", -1, "failed elections causing chaos, -1 to keep the rules'")
+	flag.Parse()
+
+	c := simulation.Config{Games: *games, Players: int8(*players), Seed: *seed, Workers: *workers}
+	switch *rules {
+	case "official":
+		c.Rules = SG.OfficialRules()
+	case "large":
+		c.Rules = SG.LargeTableRules()
+	default:
+		fail(fmt.Errorf("unknown rules %q", *rules))
+	}
+	if *veto >= 0 {
+		c.Rules.VetoUnlock = int8(*veto)
+	}
+	if *chaos >= 0 {
+		c.Rules.ChaosThreshold = int8(*chaos)
+	}
+	lib, err := bots(*liberals)
+	if err != nil {
+		fail(err)
+	}
+	fas, err := bots(*fascists)
+	if err != nil {
+		fail(err)
+	}
+	c.Bots = simulation.Teams(lib, fas)
+
+	r, err := simulation.Run(context.Background(), c)
+	if err != nil {
+		fail(err)
+	}
+	fmt.Print(r)
+}
+
+// bots returns the factory of the bots called name
+func bots(name string) (simulation.BotFactory, error) {
+	switch name {
+	case "random":
+		return simulation.Random(), nil
+	case "easy":
+		return simulation.Strategy(SG.Easy), nil
+	case "normal":
+		return simulation.Strategy(SG.Normal), nil
+	case "hard":
+		return simulation.Strategy(SG.Hard), nil
+	}
+	return nil, fmt.Errorf("unknown bots %q", name)
+}
+
+// fail prints err and exits
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "simulate:", err)
+	os.Exit(2)
+}
@@ -1,7 +1,8 @@
 package SecretGopher
 
 const (
-	NotSet int8 = -1
+	NotSet    int8 = -1 // NotSet means the seat has not been assigned yet
+	Spectator int8 = -2 // Spectator identifies a viewer that does not sit at the table
 )
 
 type state uint8 // state enumerates the possible round states
@@ -12,22 +13,86 @@ const (
 	governmentElection                 // governmentElection means the game is waiting a playerVote event
 	presidentLegislation               // presidentLegislation means the game is waiting a policyDiscard event from the president
 	chancellorLegislation              // chancellorLegislation means the game is waiting a policyDiscard event from the chancellor
-	specialPeek                        // presidentLegislation means the game is waiting a policyDiscard event from the president
-	specialInvestigate                 // presidentLegislation means the game is waiting a policyDiscard event from the president
-	specialElection                    // presidentLegislation means the game is waiting a policyDiscard event from the president
-	specialExecution                   // presidentLegislation means the game is waiting a policyDiscard event from the president
-	vetoChancellor
-	vetoPresident
-	gameEnd
+	specialPeek                        // specialPeek means the game is waiting a specialPower event from the president, using Peek
+	specialInvestigate                 // specialInvestigate means the game is waiting a specialPower event from the president, using Investigate
+	specialElection                    // specialElection means the game is waiting a specialPower event from the president, using Election
+	specialExecution                   // specialExecution means the game is waiting a specialPower event from the president, using Execution
+	vetoPresident                      // vetoPresident means the game is waiting a playerVote event from the president, answering a veto request
+	gameEnd                            // gameEnd means the game is over and accepts no more events
 )
 
-// Role is used to represent the role of a player
+// String returns the name of the state
+func (s state) String() string {
+	switch s {
+	case waitingPlayers:
+		return "waitingPlayers"
+	case chancellorCandidacy:
+		return "chancellorCandidacy"
+	case governmentElection:
+		return "governmentElection"
+	case presidentLegislation:
+		return "presidentLegislation"
+	case chancellorLegislation:
+		return "chancellorLegislation"
+	case specialPeek:
+		return "specialPeek"
+	case specialInvestigate:
+		return "specialInvestigate"
+	case specialElection:
+		return "specialElection"
+	case specialExecution:
+		return "specialExecution"
+	case vetoPresident:
+		return "vetoPresident"
+	case gameEnd:
+		return "gameEnd"
+	}
+	return "unknown"
+}
+
+// Phase is the public name of the state of a game, telling what the game is waiting for
+type Phase uint8
+
+const (
+	WaitingPlayersPhase        = Phase(waitingPlayers)        // WaitingPlayersPhase means the game is in the lobby
+	ChancellorCandidacyPhase   = Phase(chancellorCandidacy)   // ChancellorCandidacyPhase means the president has to nominate a chancellor
+	GovernmentElectionPhase    = Phase(governmentElection)    // GovernmentElectionPhase means the living players have to vote the government
+	PresidentLegislationPhase  = Phase(presidentLegislation)  // PresidentLegislationPhase means the president has to discard a policy
+	ChancellorLegislationPhase = Phase(chancellorLegislation) // ChancellorLegislationPhase means the chancellor has to discard a policy, or propose a veto
+	SpecialPeekPhase           = Phase(specialPeek)           // SpecialPeekPhase means the president has to use Peek
+	SpecialInvestigatePhase    = Phase(specialInvestigate)    // SpecialInvestigatePhase means the president has to use Investigate
+	SpecialElectionPhase       = Phase(specialElection)       // SpecialElectionPhase means the president has to use Election
+	SpecialExecutionPhase      = Phase(specialExecution)      // SpecialExecutionPhase means the president has to use Execution
+	VetoPresidentPhase         = Phase(vetoPresident)         // VetoPresidentPhase means the president has to answer a veto with a Vote
+	GameEndPhase               = Phase(gameEnd)               // GameEndPhase means the game is over
+)
+
+// String returns the name of the phase
+func (p Phase) String() string {
+	return state(p).String()
+}
+
+// Role is used to represent the role of a player.
+// Roles are described by the Rules of the game, which may define roles past the ones below
 type Role int8
 
 const (
-	LiberalParty Role = iota
-	FascistParty
-	Hitler
+	UnknownRole  Role = iota - 1 // UnknownRole means the role is hidden from the viewer
+	LiberalParty                 // LiberalParty is the role of the liberals, it is dealt to every seat left by the other roles
+	FascistParty                 // FascistParty is the role of the fascists
+	Hitler                       // Hitler is the role of the leader of the fascists
+	Communist                    // Communist is the role of the communists, who are only dealt by the rules that include them
+)
+
+// Party is used to represent the party membership of a player, the only thing an investigation reveals.
+// Parties are assigned to the roles by the Rules of the game, which may define parties past the ones below
+type Party int8
+
+const (
+	UnknownParty        Party = iota - 1 // UnknownParty means the membership is hidden from the viewer
+	LiberalMembership                    // LiberalMembership means the player is a liberal
+	FascistMembership                    // FascistMembership means the player is a fascist or Hitler
+	CommunistMembership                  // CommunistMembership means the player is a communist
 )
 
 // Vote is used to represent what a player may decide using the Ja or Nein cards that the board game uses
@@ -39,12 +104,15 @@ const (
 	Nein               // Nein means against
 )
 
-// Policy is used to represent a Policy card
-type Policy bool
+// Policy is used to represent a Policy card.
+// Every kind of policy has its own track on the boards. The Rules of the game decide how many policies of each kind
+// are in the deck, and may define kinds past the ones below
+type Policy int8
 
 const (
-	LiberalPolicy Policy = true  // LiberalPolicy means the policy is in favor of the liberal party
-	FascistPolicy Policy = false // FascistPolicy means the policy is in favor of the fascist party
+	LiberalPolicy   Policy = iota // LiberalPolicy means the policy is in favor of the liberal party
+	FascistPolicy                 // FascistPolicy means the policy is in favor of the fascist party
+	CommunistPolicy               // CommunistPolicy means the policy is in favor of the communist party
 )
 
 type SpecialPowers int8
@@ -57,11 +125,21 @@ const (
 	Execution
 )
 
-// powersTable is used to figure out what power needs to be activated for a specific round
-var powersTable = [3][6]SpecialPowers{
-	{Nothing, Nothing, Peek, Execution, Execution, Nothing},
-	{Nothing, Investigate, Election, Execution, Execution, Nothing},
-	{Investigate, Investigate, Election, Execution, Execution, Nothing},
+// String returns the name of the power
+func (p SpecialPowers) String() string {
+	switch p {
+	case Nothing:
+		return "Nothing"
+	case Peek:
+		return "Peek"
+	case Investigate:
+		return "Investigate"
+	case Election:
+		return "Election"
+	case Execution:
+		return "Execution"
+	}
+	return "unknown"
 }
 
 // GameEnding is used to signal if the game ended and how
@@ -69,8 +147,41 @@ type GameEnding int8
 
 const (
 	StillRunning        GameEnding = iota
-	LiberalPolicyWin               // LiberalPolicyWin means 5 liberal policies have been enacted
+	LiberalPolicyWin               // LiberalPolicyWin means the liberal track was filled
 	LiberalExecutionWin            // LiberalExecutionWin means hitler was killed
-	FascistPolicyWin               // FascistPolicyWin means 6 fascist policies have been enacted
-	FascistElectionWin             // FascistElectionWin means hitler was elected as chancellor
+	FascistPolicyWin               // FascistPolicyWin means the fascist track was filled
+	FascistElectionWin             // FascistElectionWin means hitler was elected as chancellor after 3 fascist policies
+	CommunistPolicyWin             // CommunistPolicyWin means the communist track was filled
 )
+
+// String returns the name of the ending
+func (e GameEnding) String() string {
+	switch e {
+	case StillRunning:
+		return "StillRunning"
+	case LiberalPolicyWin:
+		return "LiberalPolicyWin"
+	case LiberalExecutionWin:
+		return "LiberalExecutionWin"
+	case FascistPolicyWin:
+		return "FascistPolicyWin"
+	case FascistElectionWin:
+		return "FascistElectionWin"
+	case CommunistPolicyWin:
+		return "CommunistPolicyWin"
+	}
+	return "unknown"
+}
+
+// Winner returns the party that wins the game when it ends this way, or UnknownParty if the game is still running
+func (e GameEnding) Winner() Party {
+	switch e {
+	case LiberalPolicyWin, LiberalExecutionWin:
+		return LiberalMembership
+	case FascistPolicyWin, FascistElectionWin:
+		return FascistMembership
+	case CommunistPolicyWin:
+		return CommunistMembership
+	}
+	return UnknownParty
+}
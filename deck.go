@@ -3,52 +3,76 @@ package SecretGopher
 import "math/rand"
 
 type deck struct {
-	d [17]Policy // d is the stack of cards
-	p uint8      // p is the position within the stack
+	rng       *rand.Rand // rng is the random source used to shuffle the deck
+	pile      []Policy   // pile is the draw pile, its top is the first element
+	discarded []Policy   // discarded is the discard pile
+	enacted   []Policy   // enacted holds the policies placed on the boards, they never get back in the deck
 }
 
-// newDeck generates a new deck for a game and shuffles it ahead of time
-func newDeck() deck {
+// newDeck generates a new deck for a game made of counts[p] policies of every kind p,
+// and shuffles it ahead of time using r
+func newDeck(r *rand.Rand, counts []int8) deck {
+	n := 0
+	for _, c := range counts {
+		n += int(c)
+	}
 	var d = deck{
-		p: 0,
-		d: [17]Policy{
-			LiberalPolicy, LiberalPolicy, LiberalPolicy, LiberalPolicy, LiberalPolicy, LiberalPolicy,
-			FascistPolicy, FascistPolicy, FascistPolicy, FascistPolicy, FascistPolicy, FascistPolicy,
-			FascistPolicy, FascistPolicy, FascistPolicy, FascistPolicy, FascistPolicy,
-		},
+		rng:       r,
+		pile:      make([]Policy, 0, n),
+		discarded: make([]Policy, 0, n),
+		enacted:   make([]Policy, 0, n),
+	}
+	for p, c := range counts {
+		for i := int8(0); i < c; i++ {
+			d.pile = append(d.pile, Policy(p))
+		}
 	}
 	d.shuffle()
 	return d
 }
 
-// shuffle shuffles the elements of the deck pseudorandomically
+// shuffle shuffles the discard pile back into the draw pile pseudorandomically.
+// Enacted policies are left on the boards
 func (d *deck) shuffle() {
-	d.p = 0
-	rand.Shuffle(17, func(i, j int) {
-		d.d[i], d.d[j] = d.d[j], d.d[i]
+	d.pile = append(d.pile, d.discarded...)
+	d.discarded = d.discarded[:0]
+	d.rng.Shuffle(len(d.pile), func(i, j int) {
+		d.pile[i], d.pile[j] = d.pile[j], d.pile[i]
 	})
 }
 
 // draw draws the top n cards from the deck, making sure to move them away and not draw them again
 func (d *deck) draw(n uint8) []Policy {
-	var r = make([]Policy, n)
-	for i := uint8(0); i < n; i++ {
-		r[i] = d.d[d.p]
-		d.p++
-	}
-	// "If there are fewer than three tiles remaining in the policy deck at the end of a Legislative Session,
-	// they are shuffled with the Discard pile to create a new policy deck. Unused policy tiles are not revealed."
-	if d.p > 14 {
+	if len(d.pile) < int(n) {
 		d.shuffle()
 	}
+	var r = append([]Policy{}, d.pile[:n]...)
+	d.pile = d.pile[n:]
 	return r
 }
 
+// discard places the policies on the discard pile
+func (d *deck) discard(p ...Policy) {
+	d.discarded = append(d.discarded, p...)
+}
+
+// enact places the policy on its board
+func (d *deck) enact(p Policy) {
+	d.enacted = append(d.enacted, p)
+}
+
+// refill is called at the end of a legislative session.
+// "If there are fewer than three tiles remaining in the policy deck at the end of a Legislative Session,
+// they are shuffled with the Discard pile to create a new policy deck. Unused policy tiles are not revealed."
+func (d *deck) refill() {
+	if len(d.pile) < 3 {
+		d.shuffle()
+	}
+}
+
 // peek reveals the top 3 cards from the deck, making sure to leave the deck unaltered
 func (d *deck) peek() [3]Policy {
 	var r = [3]Policy{}
-	for i := uint8(0); i < 3; i++ {
-		r[i] = d.d[d.p+i]
-	}
+	copy(r[:], d.pile)
 	return r
 }
@@ -1,22 +1,174 @@
 package SecretGopher
 
+import "fmt"
+
 type (
-	// Error contains error information
-	Error struct{ Err interface{} }
+	// Error contains error information.
+	// Err is one of the Error types below, each carrying the Reason the event was rejected.
+	// Error implements error, so that errors.Is and errors.As can look into Err
+	Error struct{ Err error }
 
 	// WrongPhase is an Error type.
 	// WrongPhase means the event was sent at the wrong time
-	WrongPhase struct{}
+	WrongPhase struct{ Reason }
 
 	// GameFull is an Error type.
 	// GameFull means the number of players cannot grow anymore
-	GameFull struct{}
+	GameFull struct{ Reason }
 
 	// Unauthorized is an Error type.
 	// Unauthorized means the event was sent by the wrong authority (i.e. the wrong player)
-	Unauthorized struct{}
+	Unauthorized struct{ Reason }
 
 	// Invalid is an Error type.
 	// Invalid means the event was sent and contained Invalid data
-	Invalid struct{}
+	Invalid struct{ Reason }
+
+	// GameClosed is an Error type.
+	// GameClosed means the game was closed, or its handler was shut down
+	GameClosed struct{ Reason }
+)
+
+// Reason details why an event was rejected.
+// An Error type with an empty Reason matches, through errors.Is, any error of its type
+type Reason struct {
+	Code    Code   // Code is the machine-readable reason
+	Message string // Message is the human-readable reason
+	Field   string // Field is the field of the Command that was rejected, if any
+}
+
+// Code enumerates the reasons an event can be rejected for
+type Code uint8
+
+const (
+	NoCode              Code = iota // NoCode means no reason was given
+	UnknownSeat                     // UnknownSeat means the seat is not taken by any player
+	UnknownID                       // UnknownID means no player has the ID
+	IDTaken                         // IDTaken means another player already has the ID
+	NotEnoughPlayers                // NotEnoughPlayers means the game needs more players to start
+	TableFull                       // TableFull means every seat is taken
+	NotHost                         // NotHost means only the host can send the event
+	NotPresident                    // NotPresident means only the president can send the event
+	NotChancellor                   // NotChancellor means only the chancellor can send the event
+	SelfTarget                      // SelfTarget means the player cannot target himself
+	DeadPlayer                      // DeadPlayer means the player was executed
+	TermLimited                     // TermLimited means the player was in the last elected government
+	AlreadyVoted                    // AlreadyVoted means the player has already voted
+	InvalidVote                     // InvalidVote means the vote is neither Ja nor Nein
+	OutOfRange                      // OutOfRange means the selection is not one of the cards in hand
+	AlreadyInvestigated             // AlreadyInvestigated means the player was already investigated
+	VetoLocked                      // VetoLocked means not enough fascist policies were enacted to unlock the veto
+	VetoRefused                     // VetoRefused means the president already refused a veto in this session
+	UnknownPower                    // UnknownPower means the power does not exist
+	UnknownEvent                    // UnknownEvent means the event is not known to the game
+	NotInLobby                      // NotInLobby means the event is only accepted before the game starts
+	NotYourTurn                     // NotYourTurn means the game is waiting for another event
+	DeadlineOver                    // DeadlineOver means the deadline belongs to a phase that is over
+	Closed                          // Closed means the game was closed
+	Reserved                        // Reserved means the command can only be sent by the game itself
 )
+
+// messages maps the codes to their human-readable message
+var messages = [...]string{
+	NoCode:              "",
+	UnknownSeat:         "the seat is not taken by any player",
+	UnknownID:           "no player has the ID",
+	IDTaken:             "another player already has the ID",
+	NotEnoughPlayers:    "the game needs more players to start",
+	TableFull:           "every seat is taken",
+	NotHost:             "only the host can do this",
+	NotPresident:        "only the president can do this",
+	NotChancellor:       "only the chancellor can do this",
+	SelfTarget:          "the player cannot target himself",
+	DeadPlayer:          "the player was executed",
+	TermLimited:         "the player was in the last elected government",
+	AlreadyVoted:        "the player has already voted",
+	InvalidVote:         "the vote is neither Ja nor Nein",
+	OutOfRange:          "the selection is not one of the cards in hand",
+	AlreadyInvestigated: "the player was already investigated",
+	VetoLocked:          "the veto is not unlocked yet",
+	VetoRefused:         "the president already refused a veto",
+	UnknownPower:        "the power does not exist",
+	UnknownEvent:        "the event is not known to the game",
+	NotInLobby:          "the game has already started",
+	NotYourTurn:         "the game is waiting for something else",
+	DeadlineOver:        "the deadline belongs to a phase that is over",
+	Closed:              "the game was closed",
+	Reserved:            "the command can only be sent by the game itself",
+}
+
+// String returns the human-readable message of the code
+func (c Code) String() string {
+	if int(c) < len(messages) {
+		return messages[c]
+	}
+	return fmt.Sprintf("code %d", uint8(c))
+}
+
+// because returns the Reason for code c, about field
+func because(c Code, field string) Reason {
+	return Reason{Code: c, Message: c.String(), Field: field}
+}
+
+// describe returns the message of an error of kind, given its reason
+func (r Reason) describe(kind string) string {
+	s := "SecretGopher: " + kind
+	if r.Message != "" {
+		s += ": " + r.Message
+	}
+	if r.Field != "" {
+		s += " (" + r.Field + ")"
+	}
+	return s
+}
+
+// matches tells if the reason matches target, where the empty fields of target match anything
+func (r Reason) matches(target Reason) bool {
+	return (target.Code == NoCode || target.Code == r.Code) && (target.Field == "" || target.Field == r.Field)
+}
+
+func (e Error) Error() string {
+	if e.Err == nil {
+		return "SecretGopher: error"
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the Error type contained in e
+func (e Error) Unwrap() error { return e.Err }
+
+func (e WrongPhase) Error() string   { return e.describe("wrong phase") }
+func (e GameFull) Error() string     { return e.describe("game full") }
+func (e Unauthorized) Error() string { return e.describe("unauthorized") }
+func (e Invalid) Error() string      { return e.describe("invalid input") }
+func (e GameClosed) Error() string   { return e.describe("game closed") }
+
+// Is tells if target is a WrongPhase error with a matching reason
+func (e WrongPhase) Is(target error) bool {
+	t, ok := target.(WrongPhase)
+	return ok && e.matches(t.Reason)
+}
+
+// Is tells if target is a GameFull error with a matching reason
+func (e GameFull) Is(target error) bool {
+	t, ok := target.(GameFull)
+	return ok && e.matches(t.Reason)
+}
+
+// Is tells if target is an Unauthorized error with a matching reason
+func (e Unauthorized) Is(target error) bool {
+	t, ok := target.(Unauthorized)
+	return ok && e.matches(t.Reason)
+}
+
+// Is tells if target is an Invalid error with a matching reason
+func (e Invalid) Is(target error) bool {
+	t, ok := target.(Invalid)
+	return ok && e.matches(t.Reason)
+}
+
+// Is tells if target is a GameClosed error with a matching reason
+func (e GameClosed) Is(target error) bool {
+	t, ok := target.(GameClosed)
+	return ok && e.matches(t.Reason)
+}
@@ -1,61 +1,116 @@
 package SecretGopher
 
 import (
+	"context"
 	"math/rand"
 	"sync"
+	"time"
 )
 
 type handlerSubscription struct {
-	lenMut *sync.Mutex
-	len    uint
-	in     chan input
-	out    chan Output
+	len   uint                   // len is the number of games attached to the handler, guarded by poolMut
+	games map[*gameData]struct{} // games is the set of games attached to the handler, guarded by poolMut
+	in    chan input
+	quit  chan struct{} // quit is closed to stop the handler
+	done  chan struct{} // done is closed once the handler has stopped
 }
 
-var handlerSubscriptions = make([]handlerSubscription, 0)
+var poolMut sync.Mutex // poolMut guards the handler pool
+var handlerSubscriptions = make([]*handlerSubscription, 0)
 var maxHandlerSubscriptions uint = 10
 
 func InitHandlerGroup(max uint) {
 	if max > 0 {
+		poolMut.Lock()
 		maxHandlerSubscriptions = max
+		poolMut.Unlock()
 	} else {
 		panic("cannot use 0 as a max value in InitHandlerGroup")
 	}
 }
 
+// subscribeHandler attaches the game to a handler with some room left, starting a new handler if there is none
 func (g *Game) subscribeHandler() {
+	poolMut.Lock()
+	defer poolMut.Unlock()
 	for _, handler := range handlerSubscriptions {
-		handler.lenMut.Lock()
-		if handler.len <= maxHandlerSubscriptions {
-			g.in = handler.in
-			g.out = handler.out
-			handler.len++
-			handler.lenMut.Unlock()
+		if handler.len < maxHandlerSubscriptions {
+			handler.attach(g)
 			return
 		}
-		handler.lenMut.Unlock()
 	}
-	in := make(chan input)
-	out := make(chan Output)
-	newHandler := handlerSubscription{
-		lenMut: new(sync.Mutex),
-		len:    0,
-		in:     in,
-		out:    out,
+	newHandler := &handlerSubscription{
+		len:   0,
+		games: make(map[*gameData]struct{}),
+		in:    make(chan input),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
 	}
-	g.in = in
-	g.out = out
+	newHandler.attach(g)
 	go newHandler.handleGame()
 	handlerSubscriptions = append(handlerSubscriptions, newHandler)
 }
 
-func (h *handlerSubscription) unsubscribeHandler() {
-	h.lenMut.Lock()
-	defer h.lenMut.Unlock()
+// attach makes h the handler of g. poolMut must be held
+func (h *handlerSubscription) attach(g *Game) {
+	g.in = h.in
+	g.handler = h
+	h.games[g.data] = struct{}{}
+	h.len++
+}
+
+// unsubscribeHandler detaches game g from h, if it is still attached.
+// Once h is left without games it is removed from the pool and stopped
+func (h *handlerSubscription) unsubscribeHandler(g *gameData) {
+	poolMut.Lock()
+	defer poolMut.Unlock()
+	if _, ok := h.games[g]; !ok {
+		return
+	}
+	delete(h.games, g)
 	h.len--
+	if h.len > 0 {
+		return
+	}
+	// a handler that is no longer in the pool has already been stopped by Shutdown
+	for i, handler := range handlerSubscriptions {
+		if handler == h {
+			handlerSubscriptions = append(handlerSubscriptions[:i], handlerSubscriptions[i+1:]...)
+			close(h.quit)
+			return
+		}
+	}
+}
+
+// Shutdown stops every handler of the pool, letting each one finish the event it is handling.
+// The games served by the stopped handlers are closed: their deadlines are stopped, their subscriptions
+// are closed and every later call gets a GameClosed error. Games created during or after Shutdown are
+// served by new handlers.
+// Shutdown waits for the handlers to stop until ctx is done, in which case it returns ctx.Err()
+func Shutdown(ctx context.Context) error {
+	poolMut.Lock()
+	handlers := handlerSubscriptions
+	handlerSubscriptions = make([]*handlerSubscription, 0)
+	for _, h := range handlers {
+		close(h.quit)
+	}
+	poolMut.Unlock()
+	for _, h := range handlers {
+		select {
+		case <-h.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
 }
 
 type gameData struct {
+	rng           *rand.Rand      // rng is the only random source of the game
+	source        *countingSource // source is the source of rng when the game is seeded
+	seed          int64           // seed is the seed rng was created with
+	seeded        bool            // seeded tells if the game can be reproduced from seed
+	crypto        bool            // crypto tells if rng draws from crypto/rand
 	state         state
 	players       int8
 	deck          deck
@@ -65,13 +120,256 @@ type gameData struct {
 	nextPresident int8
 	oldGov        []int8
 	investigated  []int8
+	investigators []int8 // investigators[i] is the president that investigated investigated[i]
 	votes         []Vote
 	voted         int8
 	killed        []int8
 	policyChoice  []Policy
+	vetoDenied    bool // vetoDenied tells if the president refused a veto in the current legislative session
 	eTracker      int8
-	fTracker      int8
-	lTracker      int8
+	tracks        []int8     // tracks[p] is the number of policies p enacted
+	log           []LogEntry // log records every accepted command
+	timeouts      Timeouts
+	onTimeout     func(TimeoutApplied)
+	clock         Clock
+	timer         Timer  // timer is the pending deadline of the current phase
+	timedState    state  // timedState is the state the timer was armed for
+	deadline      uint64 // deadline identifies the deadline of the current phase
+	subscribers   []subscriber
+	eventBuffer   int        // eventBuffer is the size of the subscription buffers
+	closed        bool       // closed tells if the game was disposed of
+	lastActive    time.Time  // lastActive is when the game last accepted a command
+	identities    []Player   // identities maps the seats to the players sitting in them
+	host          int8       // host is the seat of the player who can start the game and kick players
+	ready         []bool     // ready tells which players are ready to start
+	autoStart     bool       // autoStart starts the game as soon as every player is ready
+	rules         Rules      // rules are the rules the game is played with
+	released      bool       // released tells if the game ended and gave up its handler, guarded by releaseMut
+	releaseMut    sync.Mutex // releaseMut serializes the events of a released game, which are served by their senders
+}
+
+// seat returns the seat of the player identified by id, or NotSet if there is no such player
+func (g *gameData) seat(id string) int8 {
+	for i, p := range g.identities {
+		if p.ID == id {
+			return int8(i)
+		}
+	}
+	return NotSet
+}
+
+// resolved returns e with the seats its player IDs stand for, so that the log and the subscribers see the real seats.
+// Events that cannot be resolved are returned as they are, and get rejected by handleEvent
+func (g *gameData) resolved(e event) event {
+	if b, ok := e.(byID); ok {
+		if r, err := g.resolve(b); err == nil {
+			b.event = r
+			return b
+		}
+	}
+	return e
+}
+
+// resolve replaces the player IDs of e with their seats, returning the wrapped event.
+// It fails if an ID is unknown or the event cannot be addressed by ID
+func (g *gameData) resolve(e byID) (event, error) {
+	caller, target := NotSet, NotSet
+	if e.CallerID != "" {
+		if caller = g.seat(e.CallerID); caller == NotSet {
+			return nil, Invalid{because(UnknownID, "CallerID")}
+		}
+	}
+	if e.TargetID != "" {
+		if target = g.seat(e.TargetID); target == NotSet {
+			return nil, Invalid{because(UnknownID, "TargetID")}
+		}
+	}
+	switch ev := e.event.(type) {
+	case start:
+		if caller != NotSet {
+			ev.Caller = caller
+		}
+		return ev, nil
+	case leavePlayer:
+		if caller != NotSet {
+			ev.Caller = caller
+		}
+		return ev, nil
+	case kickPlayer:
+		if caller != NotSet {
+			ev.Caller = caller
+		}
+		if target != NotSet {
+			ev.Target = target
+		}
+		return ev, nil
+	case setReady:
+		if caller != NotSet {
+			ev.Caller = caller
+		}
+		return ev, nil
+	case makeChancellor:
+		if caller != NotSet {
+			ev.Caller = caller
+		}
+		if target != NotSet {
+			ev.Proposal = target
+		}
+		return ev, nil
+	case playerVote:
+		if caller != NotSet {
+			ev.Caller = caller
+		}
+		return ev, nil
+	case proposeVeto:
+		if caller != NotSet {
+			ev.Caller = caller
+		}
+		return ev, nil
+	case policyDiscard:
+		if caller != NotSet {
+			ev.Caller = caller
+		}
+		return ev, nil
+	case specialPower:
+		if caller != NotSet {
+			ev.Caller = caller
+		}
+		if target != NotSet {
+			ev.Selection = target
+		}
+		return ev, nil
+	}
+	return nil, Invalid{because(UnknownEvent, "Kind")}
+}
+
+// alive tells if p is the seat of a player that was not executed
+func (g *gameData) alive(p int8) bool {
+	return p >= 0 && p < g.players && !search(g.killed, p)
+}
+
+// living returns the number of players that were not executed
+func (g *gameData) living() int8 {
+	return g.players - int8(len(g.killed))
+}
+
+// livingPlayers returns the seats of the players that were not executed
+func (g *gameData) livingPlayers() []int8 {
+	r := make([]int8, 0, g.living())
+	for p := int8(0); p < g.players; p++ {
+		if !search(g.killed, p) {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
+// nextLiving returns the first living player sitting after p
+func (g *gameData) nextLiving(p int8) int8 {
+	for i := int8(1); i <= g.players; i++ {
+		if n := (p + i) % g.players; !search(g.killed, n) {
+			return n
+		}
+	}
+	return NotSet
+}
+
+// termLimited tells if p took part in the last elected government and cannot be nominated chancellor.
+// The ex-president is only term-limited while more than five players are alive
+func (g *gameData) termLimited(p int8) bool {
+	return p != NotSet && (p == g.oldGov[1] || p == g.oldGov[0] && g.living() > 5)
+}
+
+// limited returns the seats of the living players that are term-limited
+func (g *gameData) limited() []int8 {
+	r := make([]int8, 0, 2)
+	for _, p := range g.oldGov {
+		if g.alive(p) && g.termLimited(p) {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
+// eligible tells if the president can nominate p as chancellor
+func (g *gameData) eligible(p int8) bool {
+	return g.nomineeCode(p) == NoCode
+}
+
+// targetCode returns why p cannot be the target of an action, or NoCode if he can
+func (g *gameData) targetCode(p int8) Code {
+	switch {
+	case p < 0 || p >= g.players:
+		return UnknownSeat
+	case search(g.killed, p):
+		return DeadPlayer
+	}
+	return NoCode
+}
+
+// powerTargetCode returns why the president cannot use a power on p, or NoCode if he can
+func (g *gameData) powerTargetCode(p int8) Code {
+	if p == g.president {
+		return SelfTarget
+	}
+	return g.targetCode(p)
+}
+
+// nomineeCode returns why the president cannot nominate p as chancellor, or NoCode if he can
+func (g *gameData) nomineeCode(p int8) Code {
+	if c := g.powerTargetCode(p); c != NoCode {
+		return c
+	}
+	if g.termLimited(p) {
+		return TermLimited
+	}
+	return NoCode
+}
+
+// investigateCode returns why the president cannot investigate p, or NoCode if he can.
+// The president cannot investigate himself, nor a player who was already investigated
+func (g *gameData) investigateCode(p int8) Code {
+	if c := g.powerTargetCode(p); c != NoCode {
+		return c
+	}
+	if search(g.investigated, p) {
+		return AlreadyInvestigated
+	}
+	return NoCode
+}
+
+// vetoCode returns why the chancellor cannot propose a veto, or NoCode if he can.
+// The veto is only unlocked late in the game, and can be asked once per session
+func (g *gameData) vetoCode() Code {
+	if g.rules.VetoUnlock == 0 || g.track(FascistPolicy) < g.rules.VetoUnlock {
+		return VetoLocked
+	}
+	if g.vetoDenied {
+		return VetoRefused
+	}
+	return NoCode
+}
+
+// voterCode returns why p cannot vote in the current election, or NoCode if he can
+func (g *gameData) voterCode(p int8) Code {
+	if c := g.targetCode(p); c != NoCode {
+		return c
+	}
+	if g.votes[p] != NoVote {
+		return AlreadyVoted
+	}
+	return NoCode
+}
+
+// advancePresident hands the presidency to the next president in line, skipping the dead players.
+// The line is not affected by special elections: after one, the presidency goes back to the player
+// sitting after the president who called it
+func (g *gameData) advancePresident() {
+	g.president = g.nextPresident
+	if search(g.killed, g.president) {
+		g.president = g.nextLiving(g.president)
+	}
+	g.nextPresident = g.nextLiving(g.president)
 }
 
 // search Returns a boolean value describing if the element exists in arr
@@ -84,51 +382,16 @@ func search(arr []int8, elem int8) bool {
 	return false
 }
 
-func (g *gameData) gameOver() GameEnding {
-	// check the fascist policies
-	if g.fTracker == 6 {
-		return FascistPolicyWin
-		// check the liberal policies
-	} else if g.lTracker == 5 {
-		return LiberalPolicyWin
-		// check if hitler is chancellor
-	} else if g.roles[g.chancellor] == Hitler {
-		return FascistElectionWin
-		// check if hitler is dead
-	} else {
-		for hitler, v := range g.roles { // find the player who is hitler
-			if v == Hitler {
-				if search(g.killed, int8(hitler)) {
-					return LiberalExecutionWin
-				}
-				break
-			}
-		}
-		// if none of the above succeed then the game is still running
-		return StillRunning
-	}
-}
-
 func (g *gameData) enactPolicyInactive() SpecialPowers {
-	s := Nothing // special powers checked only when the policy is fascist
-	switch g.policyChoice[0] {
-	case LiberalPolicy:
-		g.lTracker++
-	case FascistPolicy:
-		g.fTracker++
-		switch g.players {
-		case 5, 6:
-			s = powersTable[0][g.fTracker]
-		case 7, 8:
-			s = powersTable[1][g.fTracker]
-		case 9, 10:
-			s = powersTable[2][g.fTracker]
-		}
-	}
-	return s
+	p := g.policyChoice[0]
+	g.deck.enact(p)
+	g.deck.refill() // the legislative session is over
+	g.tracks[p]++
+	return g.power(p)
 }
 
 func (g *gameData) enactPolicyActive(out chan<- Output) {
+	g.eTracker = 0               // the government worked, the failed ones before it no longer count
 	s := g.enactPolicyInactive() // s is the special power
 	// checks if the game is over (if the policy limit for a party has been reached)
 	if o := g.gameOver(); o != StillRunning {
@@ -139,14 +402,14 @@ func (g *gameData) enactPolicyActive(out chan<- Output) {
 		}}
 		return
 	}
+	if !g.targets(s) {
+		s = Nothing // there is nobody the president can use the power on, so it is skipped
+	}
 	// update the state of the game in accordance to the special power
 	switch s {
 	case Nothing:
 		g.state = chancellorCandidacy
-		// set the next president in line
-		g.president = g.nextPresident
-		// calculate the next president in a circular fashion
-		g.nextPresident = (g.president + 1) % g.players
+		g.advancePresident() // hand the presidency to the next player in line
 	case Execution:
 		g.state = specialExecution
 	case Election:
@@ -165,17 +428,41 @@ func (g *gameData) enactPolicyActive(out chan<- Output) {
 	}}
 }
 
+// targets tells if the president has somebody to use the special power s on
+func (g *gameData) targets(s SpecialPowers) bool {
+	switch s {
+	case Investigate:
+		return len(g.choices(g.investigateCode)) != 0
+	case Election, Execution:
+		return len(g.choices(g.powerTargetCode)) != 0
+	}
+	return true
+}
+
+// vetoAccepted fails the government after a veto, reporting the outcome as a VetoAccepted
+func (g *gameData) vetoAccepted(out chan<- Output) {
+	res := make(chan Output, 1)
+	g.inactiveGov(res)
+	o := <-res
+	switch info := o.(Ok).Info.(type) {
+	case PolicyEnaction:
+		out <- Ok{Info: VetoAccepted{Forced: true, Enacted: info.Enacted, State: info.State}}
+	case NextPresident:
+		out <- Ok{Info: VetoAccepted{State: GameState(info)}}
+	default:
+		out <- o // the forced policy ended the game
+	}
+}
+
 func (g *gameData) inactiveGov(out chan<- Output) {
 	g.state = chancellorCandidacy // next step is to start a new round
-	// set the next president in line
-	g.president = g.nextPresident
-	// calculate the next president in a circular fashion
-	g.nextPresident = (g.president + 1) % g.players
+	g.advancePresident()          // hand the presidency to the next player in line
 	// advance the election tracker
 	// if advancing it triggers a forced policy enaction, do that first
-	if g.eTracker == 2 {
+	if g.eTracker == g.rules.ChaosThreshold-1 {
 		g.eTracker = 0
-		g.policyChoice = g.deck.draw(1) // draw the policy to force
+		g.oldGov[0], g.oldGov[1] = NotSet, NotSet // a forced policy clears the term limits
+		g.policyChoice = g.deck.draw(1)           // draw the policy to force
 		g.enactPolicyInactive()
 
 		// checks if the game is over (if the policy limit for a party has been reached)
@@ -202,318 +489,551 @@ func (g *gameData) inactiveGov(out chan<- Output) {
 	}
 }
 
+// startGame deals the roles, shuffles the deck and picks the first president
+func (g *gameData) startGame(out chan<- Output) {
+	g.votes = make([]Vote, g.players)          // initialize votes to the proper size
+	g.deck = newDeck(g.rng, g.policies())      // initialize deck and shuffle it
+	g.tracks = make([]int8, len(g.rules.Deck)) // initialize a track for every kind of policy
+	g.dealRoles()                              // deal the roles based on the lobby size
+	g.investigated = nil
+	g.investigators = nil
+	// the first player to be president is random
+	g.president = int8(g.rng.Intn(int(g.players)))
+	// set the next president in line
+	g.nextPresident = g.nextLiving(g.president)
+
+	g.state = chancellorCandidacy // after a president is selected, a chancellor needs to be selected
+
+	out <- Ok{Info: GameStart{GameState: g.shareState(), Rules: g.rules.clone()}} // tell the caller the game has started
+}
+
+// shareState returns the public view of the game, which is safe to hand out to anybody
 func (g *gameData) shareState() GameState {
-	return GameState{
+	return g.stateFor(Spectator)
+}
+
+// stateFor returns the GameState as seen by viewer, hiding everything the viewer cannot legitimately know.
+// Any viewer that is not a seat of the game gets the public view
+func (g *gameData) stateFor(viewer int8) GameState {
+	s := GameState{
+		Phase:           Phase(g.state),
 		ElectionTracker: g.eTracker,
-		FascistTracker:  g.fTracker,
-		LiberalTracker:  g.lTracker,
+		FascistTracker:  g.track(FascistPolicy),
+		LiberalTracker:  g.track(LiberalPolicy),
+		Tracks:          append([]int8{}, g.tracks...),
 		President:       g.president,
 		Chancellor:      g.chancellor,
-		Roles:           append([]Role{}, g.roles...), // clone the roles
+		Roles:           make([]Role, len(g.roles)),
 		Votes:           append([]Vote{}, g.votes...), // clone the votes
 		Killed:          append([]int8{}, g.killed...),
+		Living:          g.livingPlayers(),
+		Limited:         g.limited(),
+		Investigated:    append([]int8{}, g.investigated...),
+		Parties:         make([]Party, len(g.roles)),
+		Players:         append([]Player{}, g.identities...),
+		Host:            g.host,
+		Ready:           append([]bool{}, g.ready...),
+		DeckSize:        int8(len(g.deck.pile)),
+		DiscardSize:     int8(len(g.deck.discarded)),
+	}
+	for i := range s.Roles {
+		s.Roles[i] = g.knownRole(viewer, int8(i))
+		s.Parties[i] = g.knownParty(viewer, int8(i))
+	}
+	// votes are revealed all at once, only when everybody has voted
+	if g.state == governmentElection {
+		for i := range s.Votes {
+			if int8(i) != viewer {
+				s.Votes[i] = NoVote
+			}
+		}
+	}
+	// the hand of the legislative session is only seen by the government
+	switch {
+	case g.state == presidentLegislation && viewer == g.president,
+		g.state == chancellorLegislation && viewer == g.chancellor,
+		g.state == vetoPresident && (viewer == g.president || viewer == g.chancellor):
+		s.Hand = append([]Policy{}, g.policyChoice...) // clone the policy choice
+	}
+	return s
+}
+
+// knownRole returns the role of player target as known by viewer
+func (g *gameData) knownRole(viewer, target int8) Role {
+	// once the game is over every role is revealed
+	if g.state == gameEnd {
+		return g.roles[target]
+	}
+	if viewer < 0 || viewer >= int8(len(g.roles)) {
+		return UnknownRole
+	}
+	if viewer == target {
+		return g.roles[target]
+	}
+	// e.g. fascists know each other and know who Hitler is
+	if g.knows(viewer, target) {
+		return g.roles[target]
+	}
+	return UnknownRole
+}
+
+// knownParty returns the party membership of player target as known by viewer
+func (g *gameData) knownParty(viewer, target int8) Party {
+	if r := g.knownRole(viewer, target); r != UnknownRole {
+		return g.rules.PartyOf(r)
+	}
+	// the investigating president knows the membership of the investigated player
+	for i, v := range g.investigated {
+		if v == target && g.investigators[i] == viewer {
+			return g.rules.PartyOf(g.roles[target])
+		}
+	}
+	return UnknownParty
+}
+
+// closeElection counts the votes cast and either starts the legislative session or fails the government
+func (h *handlerSubscription) closeElection(g *gameData, out chan<- Output) {
+	// add up the votes
+	var r int8 = 0
+	for _, v := range g.votes {
+		switch v {
+		case Ja:
+			r++
+		case Nein:
+			r--
+		}
+	}
+	// if r is greater than 0 the election has passed
+	if r > 0 {
+		// update the term limits for the next election
+		g.oldGov[0], g.oldGov[1] = g.president, g.chancellor
+
+		// checks if the game is over (if hitler is chancellor)
+		if o := g.gameOver(); o != StillRunning {
+			g.state = gameEnd
+			out <- Ok{Info: GameEnd{
+				Why:   o,
+				State: g.shareState(),
+			}}
+			return // end the game
+		}
+		g.state = presidentLegislation // next step is to let the president choose a card to discard
+		g.vetoDenied = false
+		g.policyChoice = g.deck.draw(3)
+		// send a successful election result and notify the cards the president has to choose from
+		// in the field 'Hand'
+		out <- Ok{Info: LegislationPresident{
+			Hand:  append([]Policy{}, g.policyChoice...), // clone the policy choice
+			State: g.shareState(),
+		}}
+	} else {
+		g.inactiveGov(out) // gov was inactive, apply rules and effects
 	}
 }
 
 // handleGame handles the game events.
+// Every event is handled by handleEvent, then its output is recorded in the game log, published to the subscribers
+// and sent to the caller through the reply channel of the event, stripped of what the caller cannot see
+// Once a game ends it is released, and when the handler is stopped the games still attached to it are disposed of
 func (h *handlerSubscription) handleGame() {
-	in, out := h.in, h.out
-	defer close(out)
-	defer close(in)
-	var input input
-	var event event
-	var g *gameData
+	defer close(h.done)
+	res := make(chan Output, 1) // res holds the output of the event being handled
 	for {
-		input = <-in
-		event, g = input.event, input.gameData
-		switch event.(type) {
-		case addPlayer:
-			// if the game is accepting players
-			if g.state == waitingPlayers {
-				if g.players < 10 {
-					g.players++                                      // adds a player to the game
-					out <- Ok{Info: PlayerRegistered(g.players - 1)} // say the player was registered under the player number
-				} else {
-					out <- Error{Err: GameFull{}} // send out error
-				}
-			} else {
-				out <- Error{Err: WrongPhase{}} // send out error
+		select {
+		case input := <-h.in:
+			if input.gameData.inspect(input) {
+				continue // the game was released while the event was on its way
 			}
-		case start:
-			// if the game was accepting players
-			if g.state == waitingPlayers {
-				if g.players >= 5 {
-					g.roles = make([]Role, g.players) // initialize roles to the proper size
-					g.votes = make([]Vote, g.players) // initialize votes to the proper size
-					g.deck = newDeck()                // initialize deck and shuffle it
-
-					g.roles[rand.Intn(int(g.players))] = Hitler // set one player as Hitler
-					var nF int                                  // number of fascists based on the lobby size
-					switch g.players {
-					case 5, 6:
-						g.investigated = nil
-						nF = 1
-					case 7, 8:
-						g.investigated = make([]int8, 0, 1)
-						nF = 2
-					case 9, 10:
-						g.investigated = make([]int8, 0, 2)
-						nF = 3
-					}
-					// assign nF FascistParty roles randomly
-					for i := 0; i < nF; {
-						// extract a player
-						// if the role for that player is not FascistParty or Hitler, set him as FascistParty
-						// and increase the counter
-						if r := rand.Intn(int(g.players)); g.roles[r] == LiberalParty {
-							g.roles[r] = FascistParty
-							i++
-						}
-					}
-					// the first player to be president is random
-					g.president = int8(rand.Intn(int(g.players)))
-					// set the next president in line
-					g.nextPresident = (g.president + 1) % g.players
+			if h.serve(input, res) {
+				h.release(input.gameData)
+			}
+		case <-h.quit:
+			poolMut.Lock()
+			for g := range h.games {
+				g.dispose()
+			}
+			poolMut.Unlock()
+			return
+		}
+	}
+}
+
+// serve handles the event of input, using res to collect its output. It tells if the game has ended
+func (h *handlerSubscription) serve(input input, res chan Output) bool {
+	event := input.gameData.resolved(input.event)
+	h.handleEvent(input.gameData, event, res)
+	ended := input.gameData.state == gameEnd
+	select {
+	case o := <-res:
+		if e, ok := input.gameData.record(event, o); ok {
+			input.gameData.lastActive = input.gameData.clock.Now()
+			input.gameData.publish(e)
+		}
+		h.schedule(input.gameData)
+		input.reply <- input.gameData.reply(event, o)
+	default:
+		// the event produced no output
+	}
+	return ended
+}
+
+// release detaches the ended game g from h, so that finished games do not hold on to the handler pool.
+// The game keeps its data, and its later events are served by their senders through inspect
+func (h *handlerSubscription) release(g *gameData) {
+	g.releaseMut.Lock()
+	g.released = true
+	g.releaseMut.Unlock()
+	h.unsubscribeHandler(g)
+}
 
-					g.state = chancellorCandidacy // after a president is selected, a chancellor needs to be selected
+// inspect serves input on behalf of the sender if the game was released, telling if it did.
+// A released game has ended and has no deadline left, so its events only need to be served one at a time
+func (g *gameData) inspect(input input) bool {
+	g.releaseMut.Lock()
+	defer g.releaseMut.Unlock()
+	if !g.released {
+		return false
+	}
+	new(handlerSubscription).serve(input, make(chan Output, 1))
+	return true
+}
+
+// dispose stops the deadline of the game and closes its subscriptions.
+// Once disposed of, the game rejects every event
+func (g *gameData) dispose() {
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	for _, s := range g.subscribers {
+		close(s.ch)
+	}
+	g.subscribers = nil
+	g.closed = true
+}
 
-					out <- Ok{Info: GameStart(g.shareState())} // tell the caller the game has started
+// handleEvent handles a single event for game g, sending its output on out
+func (h *handlerSubscription) handleEvent(g *gameData, event event, out chan<- Output) {
+	if g.closed {
+		out <- Error{Err: GameClosed{because(Closed, "")}} // send out error
+		return
+	}
+	switch event.(type) {
+	case closeGame:
+		g.dispose()
+		out <- Ok{}
+	case addPlayer:
+		e := event.(addPlayer)
+		// if the game is accepting players
+		if g.state == waitingPlayers {
+			if g.players >= g.rules.MaxPlayers {
+				out <- Error{Err: GameFull{because(TableFull, "")}} // send out error
+			} else if e.ID != "" && g.seat(e.ID) != NotSet {
+				out <- Error{Err: Invalid{because(IDTaken, "CallerID")}} // send out error
+			} else {
+				g.players++ // adds a player to the game
+				g.identities = append(g.identities, Player{ID: e.ID, Name: e.Name})
+				g.ready = append(g.ready, false)
+				if g.host == NotSet {
+					g.host = g.players - 1 // the first player to join hosts the game
 				}
+				out <- Ok{Info: PlayerRegistered(g.players - 1)} // say the player was registered under the player number
+			}
+		} else {
+			out <- Error{Err: WrongPhase{because(NotInLobby, "")}} // send out error
+		}
+	case byID:
+		if e, err := g.resolve(event.(byID)); err == nil {
+			h.handleEvent(g, e, out)
+		} else {
+			out <- Error{Err: err} // send out error
+		}
+	case start:
+		e := event.(start)
+		// if the game was accepting players
+		if g.state == waitingPlayers {
+			if e.Caller != NotSet && e.Caller != g.host {
+				out <- Error{Err: Unauthorized{because(NotHost, "Caller")}} // only the host can start the game
+			} else if g.players >= g.rules.MinPlayers {
+				g.startGame(out)
 			} else {
-				out <- Error{Err: WrongPhase{}} // send out error
+				out <- Error{Err: Invalid{because(NotEnoughPlayers, "")}} // send out error
 			}
-		case makeChancellor:
-			// if the game was accepting players
-			if g.state == chancellorCandidacy {
-				e := event.(makeChancellor)
-				if e.Caller == g.president {
-					if !search(g.oldGov, e.Proposal) {
-						g.chancellor = e.Proposal
-						g.state = governmentElection
-						g.votes = make([]Vote, g.players) // reset votes
-						g.voted = 0
-						out <- Ok{Info: ElectionStart(g.shareState())} // say the chancellor registration was successful
-					} else {
-						out <- Error{Err: Invalid{}} // send out error
-					}
+		} else {
+			out <- Error{Err: WrongPhase{because(NotInLobby, "")}} // send out error
+		}
+	case leavePlayer:
+		e := event.(leavePlayer)
+		if g.state != waitingPlayers {
+			out <- Error{Err: WrongPhase{because(NotInLobby, "")}} // players can only leave the lobby
+		} else if e.Caller < 0 || e.Caller >= g.players {
+			out <- Error{Err: Invalid{because(UnknownSeat, "Caller")}} // send out error
+		} else {
+			p := g.identities[e.Caller]
+			g.removePlayer(e.Caller)
+			if !g.tryAutoStart(out) {
+				out <- Ok{Info: PlayerLeft{Seat: e.Caller, Player: p, State: g.shareState()}}
+			}
+		}
+	case kickPlayer:
+		e := event.(kickPlayer)
+		if g.state != waitingPlayers {
+			out <- Error{Err: WrongPhase{because(NotInLobby, "")}} // players can only be kicked from the lobby
+		} else if e.Caller != g.host {
+			out <- Error{Err: Unauthorized{because(NotHost, "Caller")}} // only the host can kick players
+		} else if e.Target < 0 || e.Target >= g.players {
+			out <- Error{Err: Invalid{because(UnknownSeat, "Selection")}} // send out error
+		} else if e.Target == e.Caller {
+			out <- Error{Err: Invalid{because(SelfTarget, "Selection")}} // send out error
+		} else {
+			p := g.identities[e.Target]
+			g.removePlayer(e.Target)
+			if !g.tryAutoStart(out) {
+				out <- Ok{Info: PlayerLeft{Seat: e.Target, Player: p, Kicked: true, State: g.shareState()}}
+			}
+		}
+	case setReady:
+		e := event.(setReady)
+		if g.state != waitingPlayers {
+			out <- Error{Err: WrongPhase{because(NotInLobby, "")}} // send out error
+		} else if e.Caller < 0 || e.Caller >= g.players {
+			out <- Error{Err: Invalid{because(UnknownSeat, "Caller")}} // send out error
+		} else {
+			g.ready[e.Caller] = e.Ready
+			if !g.tryAutoStart(out) {
+				out <- Ok{Info: ReadyChanged{Seat: e.Caller, Ready: e.Ready, State: g.shareState()}}
+			}
+		}
+	case makeChancellor:
+		// if the game was accepting players
+		if g.state == chancellorCandidacy {
+			e := event.(makeChancellor)
+			if e.Caller == g.president {
+				if c := g.nomineeCode(e.Proposal); c == NoCode {
+					g.chancellor = e.Proposal
+					g.state = governmentElection
+					g.votes = make([]Vote, g.players) // reset votes
+					g.voted = 0
+					out <- Ok{Info: ElectionStart(g.shareState())} // say the chancellor registration was successful
 				} else {
-					out <- Error{Err: Unauthorized{}} // send out error
+					out <- Error{Err: Invalid{because(c, "Proposal")}} // send out error
 				}
 			} else {
-				out <- Error{Err: WrongPhase{}} // send out error
+				out <- Error{Err: Unauthorized{because(NotPresident, "Caller")}} // send out error
 			}
-		case playerVote:
-			e := event.(playerVote)
-			switch g.state {
-			case governmentElection:
-				// check that the vote is valid
-				if v := e.Vote; v == Ja || v == Nein {
-					// if the user hasn't voted yet
-					if g.votes[e.Caller] == NoVote {
-						g.voted++
-						g.votes[e.Caller] = v // register the vote
-						// if all players have cast a vote
-						if g.voted == g.players {
-							// add up the votes
-							var r int8 = 0
-							for _, v := range g.votes {
-								switch v {
-								case Ja:
-									r++
-								case Nein:
-									r--
-								}
-							}
-							// if r is greater than 0 the election has passed
-							if r > 0 {
-								// update the term limits for the next election
-								g.oldGov[0], g.oldGov[1] = g.president, g.chancellor
-
-								// checks if the game is over (if hitler is chancellor)
-								if o := g.gameOver(); o != StillRunning {
-									out <- Ok{Info: GameEnd{
-										Why:   o,
-										State: g.shareState(),
-									}}
-									h.unsubscribeHandler()
-									continue // end the game
-								}
-								g.state = presidentLegislation // next step is to let the president choose a card to discard
-								g.policyChoice = g.deck.draw(3)
-								// send a successful election result and notify the cards the president has to choose from
-								// in the field 'Hand'
-								out <- Ok{Info: LegislationPresident{
-									Hand:  append([]Policy{}, g.policyChoice...), // clone the policy choice
-									State: g.shareState(),
-								}}
-							} else {
-								g.inactiveGov(out) // gov was inactive, apply rules and effects
-								if g.state == gameEnd {
-									h.unsubscribeHandler()
-									continue
-								}
-							}
-						} else {
-							out <- Ok{Info: VoteRegistered{}} // vote has been registered
-						}
+		} else {
+			out <- Error{Err: WrongPhase{because(NotYourTurn, "")}} // send out error
+		}
+	case playerVote:
+		e := event.(playerVote)
+		switch g.state {
+		case governmentElection:
+			// check that the vote is valid
+			if v := e.Vote; v == Ja || v == Nein {
+				// if the user is alive and hasn't voted yet
+				if c := g.voterCode(e.Caller); c == NoCode {
+					g.voted++
+					g.votes[e.Caller] = v // register the vote
+					// if all living players have cast a vote
+					if g.voted == g.living() {
+						h.closeElection(g, out)
 					} else {
-						// unauthorized vote as user has already voted
-						out <- Error{Err: Unauthorized{}} // send out error
+						out <- Ok{Info: VoteRegistered{}} // vote has been registered
 					}
 				} else {
-					out <- Error{Err: Invalid{}} // invalid vote error
+					// unauthorized vote as user is dead or has already voted
+					out <- Error{Err: Unauthorized{because(c, "Caller")}} // send out error
 				}
-			case vetoChancellor:
-				if e.Caller == g.chancellor {
-					if e.Vote == Ja {
-						g.state = vetoPresident
-						out <- Ok{Info: VetoRequest(g.shareState())}
-					} else {
-						g.enactPolicyActive(out)
-						if g.state == gameEnd {
-							h.unsubscribeHandler()
-							continue
-						}
-					}
-				} else {
-					out <- Error{Err: Unauthorized{}} // send out error
+			} else {
+				out <- Error{Err: Invalid{because(InvalidVote, "Vote")}} // invalid vote error
+			}
+		case vetoPresident:
+			if e.Caller == g.president {
+				switch e.Vote {
+				case Ja:
+					g.deck.discard(g.policyChoice...) // the vetoed policies are discarded
+					g.deck.refill()                   // the legislative session is over
+					g.vetoAccepted(out)
+				case Nein:
+					g.vetoDenied = true // the chancellor cannot ask again
+					g.state = chancellorLegislation
+					out <- Ok{Info: VetoRejected(g.shareState())}
+				default:
+					out <- Error{Err: Invalid{because(InvalidVote, "Vote")}} // invalid vote error
 				}
-			case vetoPresident:
-				if e.Caller == g.president {
-					if e.Vote == Ja {
-						g.inactiveGov(out) // gov was inactive, apply rules and effects
-					} else {
-						g.enactPolicyActive(out)
-					}
-					if g.state == gameEnd {
-						h.unsubscribeHandler()
-						continue
-					}
+			} else {
+				out <- Error{Err: Unauthorized{because(NotPresident, "Caller")}} // send out error
+			}
+		default:
+			out <- Error{Err: WrongPhase{because(NotYourTurn, "")}} // send out error
+		}
+	case policyDiscard:
+		e := event.(policyDiscard)
+		switch g.state {
+		case presidentLegislation:
+			if e.Caller == g.president {
+				if s := e.Selection; s < 3 {
+					g.state = chancellorLegislation
+					g.deck.discard(g.policyChoice[s])
+					g.policyChoice = append(g.policyChoice[:s], g.policyChoice[s+1:]...)
+					// send a successful result and notify the chancellor has to choose from
+					// the field 'Hand'
+					out <- Ok{Info: LegislationChancellor{
+						Hand:  append([]Policy{}, g.policyChoice...), // clone the policy choice
+						State: g.shareState(),
+					}}
 				} else {
-					out <- Error{Err: Unauthorized{}} // send out error
+					out <- Error{Err: Invalid{because(OutOfRange, "Selection")}} // send out error
 				}
-			default:
-				out <- Error{Err: WrongPhase{}} // send out error
+			} else {
+				out <- Error{Err: Unauthorized{because(NotPresident, "Caller")}} // send out error
 			}
-		case policyDiscard:
-			e := event.(policyDiscard)
-			switch g.state {
-			case presidentLegislation:
-				if e.Caller == g.president {
-					if s := e.Selection; s < 3 {
-						g.state = chancellorLegislation
-						g.policyChoice = append(g.policyChoice[:s], g.policyChoice[s+1:]...)
-						// send a successful result and notify the chancellor has to choose from
-						// the field 'Hand'
-						out <- Ok{Info: LegislationChancellor{
-							Hand:  append([]Policy{}, g.policyChoice...), // clone the policy choice
-							State: g.shareState(),
-						}}
-					}
+		case chancellorLegislation:
+			if e.Caller == g.chancellor {
+				if s := e.Selection; s < 2 {
+					g.deck.discard(g.policyChoice[s])
+					g.policyChoice = append(g.policyChoice[:s], g.policyChoice[s+1:]...)
+					g.enactPolicyActive(out)
 				} else {
-					out <- Error{Err: Unauthorized{}} // send out error
+					out <- Error{Err: Invalid{because(OutOfRange, "Selection")}} // send out error
 				}
-			case chancellorLegislation:
-				if e.Caller == g.chancellor {
-					if s := e.Selection; s < 2 {
-						g.policyChoice = append(g.policyChoice[:s], g.policyChoice[s+1:]...)
-						if g.fTracker == 5 {
-							// send out a veto request
-							g.state = vetoChancellor
-							out <- Ok{Info: VetoRequest(g.shareState())}
-						} else {
-							g.enactPolicyActive(out)
-							if g.state == gameEnd {
-								h.unsubscribeHandler()
-								continue
-							}
-						}
-					}
+			} else {
+				out <- Error{Err: Unauthorized{because(NotChancellor, "Caller")}} // send out error
+			}
+		default:
+			out <- Error{Err: WrongPhase{because(NotYourTurn, "")}} // send out error
+		}
+	case proposeVeto:
+		e := event.(proposeVeto)
+		if g.state == chancellorLegislation {
+			if e.Caller == g.chancellor {
+				if c := g.vetoCode(); c != NoCode {
+					out <- Error{Err: Invalid{because(c, "")}} // send out error
 				} else {
-					out <- Error{Err: Unauthorized{}} // send out error
+					g.state = vetoPresident
+					out <- Ok{Info: VetoProposed{
+						Hand:  append([]Policy{}, g.policyChoice...), // clone the policy choice
+						State: g.shareState(),
+					}}
 				}
-			default:
-				out <- Error{Err: WrongPhase{}} // send out error
+			} else {
+				out <- Error{Err: Unauthorized{because(NotChancellor, "Caller")}} // send out error
 			}
-		case specialPower:
-			e := event.(specialPower)
-			if e.Caller == g.president {
-				switch e.Power {
-				case Peek:
-					if g.state == specialPeek {
+		} else {
+			out <- Error{Err: WrongPhase{because(NotYourTurn, "")}} // send out error
+		}
+	case specialPower:
+		e := event.(specialPower)
+		if e.Caller == g.president {
+			switch e.Power {
+			case Peek:
+				if g.state == specialPeek {
+					g.state = chancellorCandidacy
+					g.advancePresident() // hand the presidency to the next player in line
+					out <- Ok{Info: SpecialPowerFeedback{
+						Feedback: g.deck.peek(),
+						State:    g.shareState(),
+					}}
+				} else {
+					out <- Error{Err: WrongPhase{because(NotYourTurn, "Power")}} // send out error
+				}
+			case Election:
+				if g.state == specialElection {
+					// the president cannot choose himself
+					if c := g.powerTargetCode(e.Selection); c == NoCode {
+						g.president = e.Selection
 						g.state = chancellorCandidacy
-						// set the next president in line
-						g.president = g.nextPresident
-						// calculate the next president in a circular fashion
-						g.nextPresident = (g.president + 1) % g.players
-						out <- Ok{Info: SpecialPowerFeedback{
-							Feedback: g.deck.peek(),
-							State:    g.shareState(),
-						}} // send out error
-					} else {
-						out <- Error{Err: WrongPhase{}} // send out error
-					}
-				case Election:
-					if g.state == specialElection {
-						// the president cannot choose himself
-						if e.Selection < g.players && e.Selection != g.president {
-							g.president = e.Selection
-							g.state = chancellorCandidacy
-							out <- Ok{Info: SpecialPowerFeedback{
-								Feedback: g.deck.peek(),
-								State:    g.shareState(),
-							}}
-						} else {
-							out <- Error{Err: Invalid{}} // send out error
-						}
+						out <- Ok{Info: SpecialPowerFeedback{State: g.shareState()}}
 					} else {
-						out <- Error{Err: WrongPhase{}} // send out error
+						out <- Error{Err: Invalid{because(c, "Selection")}} // send out error
 					}
-				case Execution:
-					if g.state == specialExecution {
-						if e.Selection < g.players && !search(g.killed, e.Selection) {
-							// set the next president in line
-							g.president = g.nextPresident
-							// calculate the next president in a circular fashion
-							g.nextPresident = (g.president + 1) % g.players
-							g.killed[len(g.killed)] = e.Selection
-							g.state = chancellorCandidacy
-							out <- Ok{Info: SpecialPowerFeedback{
+				} else {
+					out <- Error{Err: WrongPhase{because(NotYourTurn, "Power")}} // send out error
+				}
+			case Execution:
+				if g.state == specialExecution {
+					if c := g.powerTargetCode(e.Selection); c == NoCode {
+						g.killed = append(g.killed, e.Selection)
+						// checks if the game is over (if hitler was killed)
+						if o := g.gameOver(); o != StillRunning {
+							g.state = gameEnd
+							out <- Ok{Info: GameEnd{
+								Why:   o,
 								State: g.shareState(),
 							}}
-						} else {
-							out <- Error{Err: Invalid{}} // send out error
+							return
 						}
+						g.advancePresident() // hand the presidency to the next player in line
+						g.state = chancellorCandidacy
+						out <- Ok{Info: SpecialPowerFeedback{
+							State: g.shareState(),
+						}}
 					} else {
-						out <- Error{Err: WrongPhase{}} // send out error
+						out <- Error{Err: Invalid{because(c, "Selection")}} // send out error
 					}
-				case Investigate:
-					if g.state == specialInvestigate {
-						if e.Selection < g.players && !search(g.investigated, e.Selection) {
-							g.investigated = append(g.investigated, e.Selection)
-							g.state = chancellorCandidacy
-							// set the next president in line
-							g.president = g.nextPresident
-							// calculate the next president in a circular fashion
-							g.nextPresident = (g.president + 1) % g.players
-							out <- Ok{Info: SpecialPowerFeedback{
-								Feedback: g.roles[e.Selection],
-								State:    g.shareState(),
-							}}
-						} else {
-							out <- Error{Err: Invalid{}} // send out error
-						}
+				} else {
+					out <- Error{Err: WrongPhase{because(NotYourTurn, "Power")}} // send out error
+				}
+			case Investigate:
+				if g.state == specialInvestigate {
+					if c := g.investigateCode(e.Selection); c == NoCode {
+						g.investigated = append(g.investigated, e.Selection)
+						g.investigators = append(g.investigators, e.Caller)
+						g.state = chancellorCandidacy
+						g.advancePresident() // hand the presidency to the next player in line
+						out <- Ok{Info: SpecialPowerFeedback{
+							Feedback: g.rules.PartyOf(g.roles[e.Selection]),
+							State:    g.shareState(),
+						}}
 					} else {
-						out <- Error{Err: WrongPhase{}} // send out error
+						out <- Error{Err: Invalid{because(c, "Selection")}} // send out error
 					}
-				default:
-					out <- Error{Err: Invalid{}} // send out error
+				} else {
+					out <- Error{Err: WrongPhase{because(NotYourTurn, "Power")}} // send out error
 				}
-			} else {
-				out <- Error{Err: Unauthorized{}} // send out error
+			default:
+				out <- Error{Err: Invalid{because(UnknownPower, "Power")}} // send out error
 			}
-		default:
-			out <- Error{Err: Invalid{}} // send out error for invalid event
+		} else {
+			out <- Error{Err: Unauthorized{because(NotPresident, "Caller")}} // send out error
+		}
+	case timeout:
+		if d := event.(timeout).Deadline; d == 0 || d == g.deadline {
+			h.applyTimeout(g, out)
+		} else {
+			out <- Error{Err: WrongPhase{because(DeadlineOver, "")}} // the deadline belongs to a phase that is over
+		}
+	case viewState:
+		out <- g.stateFor(event.(viewState).Viewer)
+	case viewActions:
+		out <- g.legalActions(event.(viewActions).Viewer)
+	case snapshotGame:
+		out <- g.snapshot()
+	case subscribe:
+		e := event.(subscribe)
+		if g.state == gameEnd {
+			close(e.ch) // there is nothing left to see
+		} else {
+			g.subscribers = append(g.subscribers, subscriber{viewer: e.Viewer, ch: e.ch})
+		}
+		out <- Ok{}
+	case unsubscribe:
+		g.unsubscribe(event.(unsubscribe).ch)
+		out <- Ok{}
+	case viewSeat:
+		out <- g.seat(event.(viewSeat).ID)
+	case viewSummary:
+		out <- summary{state: g.state, players: g.players, lastActive: g.lastActive}
+	case viewLog:
+		out <- Log{
+			Seed:      g.seed,
+			Seeded:    g.seeded,
+			Timeouts:  g.timeouts,
+			Rules:     g.rules.clone(),
+			AutoStart: g.autoStart,
+			Entries:   append([]LogEntry{}, g.log...),
 		}
+	default:
+		out <- Error{Err: Invalid{because(UnknownEvent, "")}} // send out error for invalid event
 	}
 }
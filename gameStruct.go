@@ -1,31 +1,95 @@
 package SecretGopher
 
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
 // Game is the interface to the event handler
 type Game struct {
-	data gameData
-	in   chan input
-	out  chan Output
+	data    *gameData
+	in      chan input // in is the input channel of handler
+	handler *handlerSubscription
+}
+
+// Player is the identity of a player sitting at the table
+type Player struct {
+	ID   string // ID identifies the player, it is unique within a game
+	Name string // Name is the name the player is displayed with
 }
 
 // GameState is a standalone type.
 // GameState represents an instant of a game. All data contained in the struct is thread safe
-// Depending on the Output type this struct is in, some values may be missing
+// Depending on the Output type this struct is in, some values may be missing.
+// A GameState is always a view on the game: the states carried by Output types are the public view,
+// while StateFor gives out the view of a single player. Hidden roles are reported as UnknownRole
 type GameState struct {
-	ElectionTracker int8   // ElectionTracker cycles from 0 to 3
-	FascistTracker  int8   // FascistTracker starts at 0 ( no cards ), ends at 6 ( 6 slots )
-	LiberalTracker  int8   // LiberalTracker starts at 0 ( no cards ), ends at 5 ( 5 slots )
-	President       int8   // President is the current President (elected or candidate)
-	Chancellor      int8   // Chancellor is the current Chancellor (elected or candidate)
-	Roles           []Role // Roles is an array that maps a player's index to his role
-	Votes           []Vote // Votes saves the votes for each player this round
-	Killed          []int8 // Killed is a set that memorizes the ids of dead players
-	Limited         []int8 // Limited is a set that memorizes the ids of limited players
-}
-
-// NewGame creates a game structure and subscribes a goroutine to listen to the events for the game
+	Phase           Phase    // Phase is what the game is waiting for
+	ElectionTracker int8     // ElectionTracker counts the failed governments in a row, it resets on reaching the ChaosThreshold of the Rules
+	FascistTracker  int8     // FascistTracker is Tracks[FascistPolicy], its length is set by the win conditions of the Rules
+	LiberalTracker  int8     // LiberalTracker is Tracks[LiberalPolicy], its length is set by the win conditions of the Rules
+	Tracks          []int8   // Tracks maps every kind of policy to the number of policies of that kind enacted, generalizing the trackers above
+	President       int8     // President is the current President (elected or candidate)
+	Chancellor      int8     // Chancellor is the current Chancellor (elected or candidate)
+	Roles           []Role   // Roles is an array that maps a player's index to his role, as known by the viewer
+	Votes           []Vote   // Votes saves the votes for each player this round, hidden until everybody voted
+	Killed          []int8   // Killed is a set that memorizes the ids of dead players
+	Living          []int8   // Living is the set of the ids of the players still alive
+	Limited         []int8   // Limited is a set that memorizes the ids of limited players
+	Investigated    []int8   // Investigated is a set that memorizes the ids of the investigated players
+	Parties         []Party  // Parties maps a player's index to his party membership, as known by the viewer
+	Players         []Player // Players maps a player's index to his identity
+	Host            int8     // Host is the player who can start the game and kick players
+	Ready           []bool   // Ready tells which players are ready to start
+	DeckSize        int8     // DeckSize is the number of policies left in the draw pile
+	DiscardSize     int8     // DiscardSize is the number of policies in the discard pile
+	Hand            []Policy // Hand is the hand of the legislative session, seen by the member of the government holding it and by both during a veto
+}
+
+// Options configures a game at creation time
+type Options struct {
+	Seed       int64       // Seed is the seed of the game's random source. It is ignored if Source is set or CryptoRand is true
+	Source     rand.Source // Source, if not nil, is used as the game's random source. It must not be shared with other games
+	CryptoRand bool        // CryptoRand makes the game draw its randomness from crypto/rand, which is not reproducible
+
+	Timeouts  Timeouts             // Timeouts are the deadlines of the game phases
+	OnTimeout func(TimeoutApplied) // OnTimeout, if not nil, is called every time a deadline expires and gets applied
+	Clock     Clock                // Clock measures the deadlines. A nil Clock means the system clock
+
+	EventBuffer int // EventBuffer is the size of the buffer of every subscription. Zero means 64 events
+
+	AutoStart bool // AutoStart starts the game as soon as there are enough players and all of them are ready
+
+	Rules Rules // Rules are the rules of the game. Empty Rules mean OfficialRules
+}
+
+// NewGame creates a game structure and subscribes a goroutine to listen to the events for the game.
+// The game is seeded with the current time
 func NewGame() Game {
+	return NewGameWithOptions(Options{Seed: time.Now().UnixNano()})
+}
+
+// NewGameWithOptions creates a game structure configured by o and subscribes a goroutine to listen to the events for the game.
+// Two games created with the same Seed that receive the same inputs behave identically.
+// NewGameWithOptions panics if o.Rules are not valid: house rules should go through TryNewGame
+func NewGameWithOptions(o Options) Game {
+	G, err := TryNewGame(o)
+	if err != nil {
+		panic(err)
+	}
+	return G
+}
+
+// TryNewGame creates a game like NewGameWithOptions, but returns the first problem of o.Rules as an error
+// instead of panicking. The game keeps its own copy of the rules
+func TryNewGame(o Options) (Game, error) {
+	rules := o.Rules.orOfficial().clone()
+	if err := rules.Validate(); err != nil {
+		return Game{}, err
+	}
 	G := Game{
-		data: gameData{
+		data: &gameData{
 			state:   waitingPlayers,
 			players: 0,
 			//deck:          // initialized later
@@ -33,65 +97,161 @@ func NewGame() Game {
 			chancellor: NotSet,
 			//roles:         // initialized later
 			nextPresident: NotSet,
-			oldGov:        make([]int8, 2),
-			killed:        make([]int8, 2),
+			oldGov:        []int8{NotSet, NotSet},
+			killed:        make([]int8, 0, 2),
 			//investigated:  // initialized later
 			//votes:         // initialized later
 			voted: 0,
 			//policyChoice:  // initialized later
 			eTracker: 0,
-			fTracker: 0,
-			lTracker: 0,
+			//log:           // initialized later
+			timeouts:    o.Timeouts,
+			onTimeout:   o.OnTimeout,
+			clock:       o.Clock,
+			timedState:  waitingPlayers,
+			eventBuffer: o.EventBuffer,
+			host:        NotSet,
+			autoStart:   o.AutoStart,
+			rules:       rules,
 		},
 	}
+	if G.data.clock == nil {
+		G.data.clock = systemClock{}
+	}
+	G.data.lastActive = G.data.clock.Now()
+	if G.data.eventBuffer <= 0 {
+		G.data.eventBuffer = defaultEventBuffer
+	}
+	switch {
+	case o.CryptoRand:
+		G.data.rng = rand.New(cryptoSource{})
+		G.data.crypto = true
+	case o.Source != nil:
+		G.data.rng = rand.New(o.Source)
+	default:
+		G.data.source = newCountingSource(o.Seed, 0)
+		G.data.rng = rand.New(G.data.source)
+		G.data.seed, G.data.seeded = o.Seed, true
+	}
 	G.subscribeHandler()
-	return G
+	return G, nil
 }
 
-func (g *Game) Start() Output {
-	g.in <- input{
-		gameData: &g.data,
-		event:    start{},
+// Seed returns the seed the game was created with.
+// The boolean is false if the game was not created from a seed and cannot be reproduced
+func (g *Game) Seed() (int64, bool) {
+	return g.data.seed, g.data.seeded
+}
+
+// send sends event e to the handler of the game and waits for its output.
+// If ctx is done first, send gives up and returns ctx.Err(): the handler will still process the event
+// if it already received it, but its output is dropped without affecting the other callers
+func (g *Game) send(ctx context.Context, e event) (Output, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	reply := make(chan Output, 1)
+	in := input{gameData: g.data, event: e, reply: reply}
+	if g.data.inspect(in) {
+		return <-reply, nil // the game ended and was released by its handler
+	}
+	select {
+	case g.in <- in:
+	case <-g.handler.done:
+		if g.data.inspect(in) {
+			return <-reply, nil
+		}
+		return Error{Err: GameClosed{because(Closed, "")}}, nil // the handler was shut down
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case o := <-reply:
+		return o, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Do sends command c to the game and waits for its Output, giving up when ctx is done.
+// A command abandoned after the game received it may still be applied
+func (g *Game) Do(ctx context.Context, c Command) (Output, error) {
+	if c.Kind == TimeoutCommand {
+		return Error{Err: Unauthorized{because(Reserved, "Kind")}}, nil // deadlines are only applied by the game itself
 	}
-	return <-g.out
+	return g.send(ctx, c.event())
+}
+
+func (g *Game) Start() Output {
+	o, _ := g.send(context.Background(), start{Caller: NotSet})
+	return o
 }
 
+// AddPlayer adds an anonymous player to the game
 func (g *Game) AddPlayer() Output {
-	g.in <- input{
-		gameData: &g.data,
-		event:    addPlayer{},
+	o, _ := g.send(context.Background(), addPlayer{})
+	return o
+}
+
+// Join adds to the game the player identified by id, displayed as name.
+// The id can then be used in place of the player's seat in every Command
+func (g *Game) Join(id, name string) Output {
+	o, _ := g.send(context.Background(), addPlayer{ID: id, Name: name})
+	return o
+}
+
+// Seat returns the seat of the player identified by id, or NotSet if there is no such player
+func (g *Game) Seat(id string) int8 {
+	o, _ := g.send(context.Background(), viewSeat{ID: id})
+	if s, ok := o.(int8); ok {
+		return s
 	}
-	return <-g.out
+	return NotSet
 }
 
 func (g *Game) Vote(c int8, v Vote) Output {
-	g.in <- input{
-		gameData: &g.data,
-		event:    playerVote{Caller: c, Vote: v},
-	}
-	return <-g.out
+	o, _ := g.send(context.Background(), playerVote{Caller: c, Vote: v})
+	return o
 }
 
 func (g *Game) MakeChancellor(c, p int8) Output {
-	g.in <- input{
-		gameData: &g.data,
-		event:    makeChancellor{Caller: c, Proposal: p},
-	}
-	return <-g.out
+	o, _ := g.send(context.Background(), makeChancellor{Caller: c, Proposal: p})
+	return o
 }
 
 func (g *Game) PolicyDiscard(c int8, s uint8) Output {
-	g.in <- input{
-		gameData: &g.data,
-		event:    policyDiscard{Caller: c, Selection: s},
-	}
-	return <-g.out
+	o, _ := g.send(context.Background(), policyDiscard{Caller: c, Selection: s})
+	return o
+}
+
+// Veto is sent by chancellor c to ask the president to veto the agenda.
+// The president answers with Vote
+func (g *Game) Veto(c int8) Output {
+	o, _ := g.send(context.Background(), proposeVeto{Caller: c})
+	return o
 }
 
 func (g *Game) SpecialPower(c int8, p SpecialPowers, s int8) Output {
-	g.in <- input{
-		gameData: &g.data,
-		event:    specialPower{Caller: c, Power: p, Selection: s},
+	o, _ := g.send(context.Background(), specialPower{Caller: c, Power: p, Selection: s})
+	return o
+}
+
+// StateFor returns the GameState as seen by player.
+// The view only reveals what the player legitimately knows: his own role, his fellow fascists if he is one,
+// and the party membership of the players he investigated as president.
+// Passing Spectator (or any value that is not a seat) returns the public view
+// A closed game returns an empty GameState
+func (g *Game) StateFor(player int8) GameState {
+	o, _ := g.send(context.Background(), viewState{Viewer: player})
+	s, _ := o.(GameState)
+	return s
+}
+
+// Close disposes of the game: its deadline is stopped, its subscriptions are closed and it is detached
+// from its handler, which stops once it serves no game. Every call made after Close gets a GameClosed error.
+// Games are not closed when they end: they give their handler back, but can still be inspected until Close
+func (g *Game) Close() {
+	if o, _ := g.send(context.Background(), closeGame{}); isOk(o) {
+		g.handler.unsubscribeHandler(g.data)
 	}
-	return <-g.out
 }
@@ -0,0 +1,3 @@
+module github.com/nylone/SecretGopher
+
+go 1.21
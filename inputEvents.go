@@ -4,17 +4,45 @@ type (
 	input struct {
 		*gameData
 		event
+		reply chan<- Output // reply receives the output of the event, it must be buffered
 	}
 
 	event interface{} // event is a void interface. it's only used to simplify reading code
 
 	// addPlayer is an event type.
-	// addPlayer requests that the number of players be increased by one
-	addPlayer struct{}
+	// addPlayer requests that the number of players be increased by one.
+	// The new player is identified by 'ID' and called 'Name', both may be left empty
+	addPlayer struct {
+		ID   string
+		Name string
+	}
 
 	// start is an event type.
-	// start requests that the game starts
-	start struct{}
+	// start requests that the game starts on behalf of player 'Caller'.
+	// A NotSet Caller starts the game on behalf of the server
+	start struct {
+		Caller int8
+	}
+
+	// leavePlayer is an event type.
+	// leavePlayer requests that player 'Caller' leaves the lobby
+	leavePlayer struct {
+		Caller int8
+	}
+
+	// kickPlayer is an event type.
+	// kickPlayer requests that player 'Target' is removed from the lobby by player 'Caller'
+	kickPlayer struct {
+		Caller int8
+		Target int8
+	}
+
+	// setReady is an event type.
+	// setReady says whether player 'Caller' is ready to start the game
+	setReady struct {
+		Caller int8
+		Ready  bool
+	}
 
 	// makeChancellor is an event type.
 	// makeChancellor requests that player 'Proposal' is made chancellor
@@ -47,4 +75,79 @@ type (
 		Power     SpecialPowers
 		Selection int8
 	}
+
+	// proposeVeto is an event type.
+	// proposeVeto says that chancellor 'Caller' asks the president to veto the agenda
+	proposeVeto struct {
+		Caller int8
+	}
+
+	// byID is an event type.
+	// byID wraps an event whose caller and target are addressed by player ID rather than by seat.
+	// Empty IDs leave the seats of the wrapped event untouched
+	byID struct {
+		CallerID string
+		TargetID string
+		event    event
+	}
+
+	// timeout is an event type.
+	// timeout says that the deadline identified by 'Deadline' has expired.
+	// A zero Deadline applies to whatever phase the game is in
+	timeout struct {
+		Deadline uint64
+	}
+
+	// viewState is an event type.
+	// viewState requests the GameState as seen by player 'Viewer'.
+	// It does not alter the game in any way
+	viewState struct {
+		Viewer int8
+	}
+
+	// viewActions is an event type.
+	// viewActions requests the commands player 'Viewer' can send.
+	// It does not alter the game in any way
+	viewActions struct {
+		Viewer int8
+	}
+
+	// subscribe is an event type.
+	// subscribe requests that the changes of the game, as seen by 'Viewer', are sent to ch
+	subscribe struct {
+		Viewer int8
+		ch     chan Event
+	}
+
+	// unsubscribe is an event type.
+	// unsubscribe requests that the changes of the game are no longer sent to ch
+	unsubscribe struct {
+		ch <-chan Event
+	}
+
+	// closeGame is an event type.
+	// closeGame requests that the game is disposed of
+	closeGame struct{}
+
+	// viewSeat is an event type.
+	// viewSeat requests the seat of the player identified by 'ID'.
+	// It does not alter the game in any way
+	viewSeat struct {
+		ID string
+	}
+
+	// viewSummary is an event type.
+	// viewSummary requests the information a Manager keeps about the game.
+	// It does not alter the game in any way
+	viewSummary struct{}
+
+	// viewLog is an event type.
+	// viewLog requests a copy of the game log.
+	// It does not alter the game in any way
+	viewLog struct{}
+
+	// snapshotGame is an event type.
+	// snapshotGame requests a copy of the whole internal state of the game.
+	// It does not alter the game in any way
+	snapshotGame struct{}
 )
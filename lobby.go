@@ -0,0 +1,80 @@
+package SecretGopher
+
+import "context"
+
+// StartBy starts the game on behalf of player c, who must be the host.
+// Start, on the other hand, starts the game on behalf of the server and does not check the host
+func (g *Game) StartBy(c int8) Output {
+	o, _ := g.send(context.Background(), start{Caller: c})
+	return o
+}
+
+// Leave removes player c from the lobby.
+// The players sitting after c move up by one seat, and if c was the host the player in seat 0 takes over.
+// With Options.AutoStart, the game starts if the players left are enough and all of them are ready
+func (g *Game) Leave(c int8) Output {
+	o, _ := g.send(context.Background(), leavePlayer{Caller: c})
+	return o
+}
+
+// Kick removes player t from the lobby on behalf of player c, who must be the host.
+// The seats are compacted as in Leave
+func (g *Game) Kick(c, t int8) Output {
+	o, _ := g.send(context.Background(), kickPlayer{Caller: c, Target: t})
+	return o
+}
+
+// SetReady tells whether player c is ready to start.
+// If the game was created with Options.AutoStart, it starts as soon as there are enough players and all of them are ready
+func (g *Game) SetReady(c int8, ready bool) Output {
+	o, _ := g.send(context.Background(), setReady{Caller: c, Ready: ready})
+	return o
+}
+
+// removePlayer removes player p from the lobby, moving every following player up by one seat
+func (g *gameData) removePlayer(p int8) {
+	g.players--
+	g.identities = append(g.identities[:p], g.identities[p+1:]...)
+	g.ready = append(g.ready[:p], g.ready[p+1:]...)
+	switch {
+	case g.players == 0:
+		g.host = NotSet
+	case p == g.host:
+		g.host = 0 // the player who has been waiting the longest takes over
+	case p < g.host:
+		g.host--
+	}
+	// the subscriptions follow their players to their new seats
+	kept := g.subscribers[:0]
+	for _, s := range g.subscribers {
+		switch {
+		case s.viewer == p:
+			close(s.ch)
+			continue
+		case s.viewer > p:
+			s.viewer--
+		}
+		kept = append(kept, s)
+	}
+	g.subscribers = kept
+}
+
+// allReady tells if every player in the lobby is ready
+func (g *gameData) allReady() bool {
+	for _, r := range g.ready {
+		if !r {
+			return false
+		}
+	}
+	return true
+}
+
+// tryAutoStart starts the game if it starts on its own and every player left in the lobby is ready.
+// It tells if the game started, in which case the output of the start was sent on out
+func (g *gameData) tryAutoStart(out chan<- Output) bool {
+	if g.autoStart && g.players >= g.rules.MinPlayers && g.allReady() {
+		g.startGame(out) // everybody is ready, the game starts on its own
+		return true
+	}
+	return false
+}
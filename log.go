@@ -0,0 +1,182 @@
+package SecretGopher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CommandKind enumerates the commands accepted by a game
+type CommandKind uint8
+
+const (
+	AddPlayerCommand      CommandKind = iota // AddPlayerCommand is the command sent by Game.AddPlayer
+	StartCommand                             // StartCommand is the command sent by Game.Start
+	MakeChancellorCommand                    // MakeChancellorCommand is the command sent by Game.MakeChancellor
+	VoteCommand                              // VoteCommand is the command sent by Game.Vote
+	PolicyDiscardCommand                     // PolicyDiscardCommand is the command sent by Game.PolicyDiscard
+	SpecialPowerCommand                      // SpecialPowerCommand is the command sent by Game.SpecialPower
+	TimeoutCommand                           // TimeoutCommand is the command sent when a phase deadline expires
+	LeaveCommand                             // LeaveCommand is the command sent by Game.Leave
+	KickCommand                              // KickCommand is the command sent by Game.Kick
+	ReadyCommand                             // ReadyCommand is the command sent by Game.SetReady
+	VetoCommand                              // VetoCommand is the command sent by Game.Veto
+)
+
+// Command is the serializable form of an input event.
+// Only the fields used by the command Kind are meaningful.
+// Players can be addressed either by seat or by ID: a non empty CallerID takes the place of Caller, and a non empty
+// TargetID takes the place of Proposal or of the Selection of a SpecialPowerCommand or a KickCommand.
+// A StartCommand sent with a NotSet Caller starts the game on behalf of the server
+type Command struct {
+	Kind      CommandKind
+	Caller    int8          // Caller is the player sending the command
+	CallerID  string        // CallerID is the ID of the player sending the command, or of the player joining the game
+	TargetID  string        // TargetID is the ID of the player the command is aimed at
+	Name      string        // Name is the name of the player joining the game with an AddPlayerCommand
+	Proposal  int8          // Proposal is the chancellor candidate of a MakeChancellorCommand
+	Vote      Vote          // Vote is the vote of a VoteCommand
+	Power     SpecialPowers // Power is the power used by a SpecialPowerCommand
+	Selection int8          // Selection is the discarded card of a PolicyDiscardCommand or the target of a SpecialPowerCommand or a KickCommand
+	Ready     bool          // Ready is the readiness of the caller of a ReadyCommand
+}
+
+// LogEntry is an accepted command along with the Output it produced
+type LogEntry struct {
+	Seq     uint64 // Seq is the sequence number of the entry, starting from 1
+	Command Command
+	Output  Output
+}
+
+// Log is the ordered record of every command accepted by a game.
+// A Log recorded by a seeded game can be replayed to rebuild the game
+type Log struct {
+	Seed      int64    // Seed is the seed of the recorded game
+	Seeded    bool     // Seeded tells if the recorded game was created from Seed
+	Timeouts  Timeouts // Timeouts are the deadlines of the recorded game
+	Rules     Rules    // Rules are the rules of the recorded game
+	AutoStart bool     // AutoStart tells if the recorded game started on its own once everybody was ready
+	Entries   []LogEntry
+}
+
+// errNotReplayable is returned when replaying a log recorded without a seed
+var errNotReplayable = errors.New("SecretGopher: the log was not recorded by a seeded game")
+
+// event converts the command to the input event it represents
+func (c Command) event() event {
+	if c.Kind != AddPlayerCommand && (c.CallerID != "" || c.TargetID != "") {
+		return byID{CallerID: c.CallerID, TargetID: c.TargetID, event: c.seatEvent()}
+	}
+	return c.seatEvent()
+}
+
+// seatEvent converts the command to the input event it represents, ignoring the player IDs
+func (c Command) seatEvent() event {
+	switch c.Kind {
+	case AddPlayerCommand:
+		return addPlayer{ID: c.CallerID, Name: c.Name}
+	case StartCommand:
+		return start{Caller: c.Caller}
+	case LeaveCommand:
+		return leavePlayer{Caller: c.Caller}
+	case KickCommand:
+		return kickPlayer{Caller: c.Caller, Target: c.Selection}
+	case ReadyCommand:
+		return setReady{Caller: c.Caller, Ready: c.Ready}
+	case MakeChancellorCommand:
+		return makeChancellor{Caller: c.Caller, Proposal: c.Proposal}
+	case VoteCommand:
+		return playerVote{Caller: c.Caller, Vote: c.Vote}
+	case PolicyDiscardCommand:
+		return policyDiscard{Caller: c.Caller, Selection: uint8(c.Selection)}
+	case SpecialPowerCommand:
+		return specialPower{Caller: c.Caller, Power: c.Power, Selection: c.Selection}
+	case VetoCommand:
+		return proposeVeto{Caller: c.Caller}
+	case TimeoutCommand:
+		return timeout{}
+	}
+	return nil
+}
+
+// commandOf converts an input event to its Command.
+// The boolean is false if the event is not a command, i.e. it does not alter the game
+func commandOf(e event) (Command, bool) {
+	switch e := e.(type) {
+	case addPlayer:
+		return Command{Kind: AddPlayerCommand, CallerID: e.ID, Name: e.Name}, true
+	case byID:
+		c, ok := commandOf(e.event)
+		c.CallerID, c.TargetID = e.CallerID, e.TargetID
+		return c, ok
+	case start:
+		return Command{Kind: StartCommand, Caller: e.Caller}, true
+	case leavePlayer:
+		return Command{Kind: LeaveCommand, Caller: e.Caller}, true
+	case kickPlayer:
+		return Command{Kind: KickCommand, Caller: e.Caller, Selection: e.Target}, true
+	case setReady:
+		return Command{Kind: ReadyCommand, Caller: e.Caller, Ready: e.Ready}, true
+	case makeChancellor:
+		return Command{Kind: MakeChancellorCommand, Caller: e.Caller, Proposal: e.Proposal}, true
+	case playerVote:
+		return Command{Kind: VoteCommand, Caller: e.Caller, Vote: e.Vote}, true
+	case policyDiscard:
+		return Command{Kind: PolicyDiscardCommand, Caller: e.Caller, Selection: int8(e.Selection)}, true
+	case specialPower:
+		return Command{Kind: SpecialPowerCommand, Caller: e.Caller, Power: e.Power, Selection: e.Selection}, true
+	case proposeVeto:
+		return Command{Kind: VetoCommand, Caller: e.Caller}, true
+	case timeout:
+		return Command{Kind: TimeoutCommand}, true
+	}
+	return Command{}, false
+}
+
+// isOk tells if the output reports a successful interaction
+func isOk(o Output) bool {
+	_, ok := o.(Ok)
+	return ok
+}
+
+// record appends the event to the game log if it is a command that was accepted.
+// The boolean tells if the event was recorded
+func (g *gameData) record(e event, o Output) (LogEntry, bool) {
+	c, ok := commandOf(e)
+	if !ok || !isOk(o) {
+		return LogEntry{}, false
+	}
+	g.log = append(g.log, LogEntry{
+		Seq:     uint64(len(g.log)) + 1,
+		Command: c,
+		Output:  o,
+	})
+	return g.log[len(g.log)-1], true
+}
+
+// Log returns a copy of the game log.
+// A closed game returns an empty Log
+func (g *Game) Log() Log {
+	o, _ := g.send(context.Background(), viewLog{})
+	l, _ := o.(Log)
+	return l
+}
+
+// Replay rebuilds a game by sending every command of the log to a new game created with the recorded seed and deadlines.
+// Replay fails if the log was not recorded by a seeded game or if one of its commands is rejected
+func Replay(l Log) (Game, error) {
+	if !l.Seeded {
+		return Game{}, errNotReplayable
+	}
+	G, err := TryNewGame(Options{Seed: l.Seed, Timeouts: l.Timeouts, Rules: l.Rules, AutoStart: l.AutoStart})
+	if err != nil {
+		return Game{}, err
+	}
+	for _, e := range l.Entries {
+		if o, _ := G.send(context.Background(), e.Command.event()); !isOk(o) {
+			G.Close()
+			return Game{}, fmt.Errorf("SecretGopher: replayed command %d was rejected: %v", e.Seq, o)
+		}
+	}
+	return G, nil
+}
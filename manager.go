@@ -0,0 +1,252 @@
+package SecretGopher
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// codeAlphabet is the alphabet of the join codes, without the characters that are easily confused
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// codeLength is the length of the join codes
+const codeLength = 6
+
+// ManagerOptions configures a Manager
+type ManagerOptions struct {
+	FinishedTTL   time.Duration // FinishedTTL is how long a finished game is kept after its last command. Zero keeps it
+	IdleTTL       time.Duration // IdleTTL is how long a running game is kept without accepting commands. Zero keeps it
+	SweepInterval time.Duration // SweepInterval is how often the expired games are evicted. Zero leaves it to Sweep
+	Clock         Clock         // Clock measures the TTLs. A nil Clock means the system clock
+}
+
+// Manager keeps track of many games, identifying each of them by a unique ID and a short join code.
+// All of its methods are safe for concurrent use
+type Manager struct {
+	mut   sync.Mutex
+	opts  ManagerOptions
+	games map[string]*managedGame // games maps the IDs to the games
+	codes map[string]string       // codes maps the join codes to the IDs
+	timer Timer                   // timer is the next automatic sweep
+}
+
+// managedGame is a game kept by a Manager
+type managedGame struct {
+	game    *Game
+	id      string
+	code    string
+	created time.Time
+}
+
+// GameSummary describes a game kept by a Manager
+type GameSummary struct {
+	ID         string
+	Code       string    // Code is the short code players can use to join the game
+	Phase      string    // Phase is the name of the phase the game is in
+	Players    int8      // Players is the number of players in the game
+	Created    time.Time // Created is when the game was created
+	LastActive time.Time // LastActive is when the game last accepted a command
+}
+
+// summary is the information about a game needed by a Manager
+type summary struct {
+	state      state
+	players    int8
+	lastActive time.Time
+}
+
+// NewManager creates a Manager configured by o.
+// If o.SweepInterval is set, the expired games are evicted automatically until Close is called
+func NewManager(o ManagerOptions) *Manager {
+	if o.Clock == nil {
+		o.Clock = systemClock{}
+	}
+	m := &Manager{
+		opts:  o,
+		games: make(map[string]*managedGame),
+		codes: make(map[string]string),
+	}
+	if o.SweepInterval > 0 {
+		m.timer = o.Clock.AfterFunc(o.SweepInterval, m.sweepAndRearm)
+	}
+	return m
+}
+
+// Create creates a game configured by o and returns its ID along with the game.
+// It fails if o.Rules are not valid
+func (m *Manager) Create(o Options) (string, *Game, error) {
+	if o.Clock == nil {
+		o.Clock = m.opts.Clock
+	}
+	G, err := TryNewGame(o)
+	if err != nil {
+		return "", nil, err
+	}
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	mg := &managedGame{
+		game:    &G,
+		id:      m.unique(randomID, func(s string) bool { _, ok := m.games[s]; return ok }),
+		code:    m.unique(randomCode, func(s string) bool { _, ok := m.codes[s]; return ok }),
+		created: m.opts.Clock.Now(),
+	}
+	m.games[mg.id] = mg
+	m.codes[mg.code] = mg.id
+	return mg.id, mg.game, nil
+}
+
+// unique generates values until it finds one that is not taken. m.mut must be held
+func (m *Manager) unique(generate func() string, taken func(string) bool) string {
+	for {
+		if s := generate(); !taken(s) {
+			return s
+		}
+	}
+}
+
+// Get returns the game identified by id
+func (m *Manager) Get(id string) (*Game, bool) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if mg, ok := m.games[id]; ok {
+		return mg.game, true
+	}
+	return nil, false
+}
+
+// GetByCode returns the ID and the game identified by the join code.
+// Codes are not case sensitive
+func (m *Manager) GetByCode(code string) (string, *Game, bool) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if id, ok := m.codes[strings.ToUpper(code)]; ok {
+		return id, m.games[id].game, true
+	}
+	return "", nil, false
+}
+
+// List returns the summaries of all the games, from the oldest to the newest
+func (m *Manager) List() []GameSummary {
+	var r []GameSummary
+	for _, mg := range m.managed() {
+		s := mg.game.summary()
+		r = append(r, GameSummary{
+			ID:         mg.id,
+			Code:       mg.code,
+			Phase:      s.state.String(),
+			Players:    s.players,
+			Created:    mg.created,
+			LastActive: s.lastActive,
+		})
+	}
+	return r
+}
+
+// Remove closes the game identified by id and forgets about it.
+// It returns false if there is no such game
+func (m *Manager) Remove(id string) bool {
+	m.mut.Lock()
+	mg, ok := m.games[id]
+	if ok {
+		delete(m.games, id)
+		delete(m.codes, mg.code)
+	}
+	m.mut.Unlock()
+	if ok {
+		mg.game.Close()
+	}
+	return ok
+}
+
+// Sweep evicts the finished games older than FinishedTTL and the running games idle for longer than IdleTTL.
+// It returns the IDs of the evicted games
+func (m *Manager) Sweep() []string {
+	now := m.opts.Clock.Now()
+	var evicted []string
+	for _, mg := range m.managed() {
+		s := mg.game.summary()
+		ttl := m.opts.IdleTTL
+		if s.state == gameEnd {
+			ttl = m.opts.FinishedTTL
+		}
+		if ttl > 0 && now.Sub(s.lastActive) >= ttl && m.Remove(mg.id) {
+			evicted = append(evicted, mg.id)
+		}
+	}
+	return evicted
+}
+
+// Close stops the automatic eviction and closes every game of the manager
+func (m *Manager) Close() {
+	m.mut.Lock()
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	m.mut.Unlock()
+	for _, mg := range m.managed() {
+		m.Remove(mg.id)
+	}
+}
+
+// sweepAndRearm evicts the expired games and schedules the next sweep, unless the manager was closed
+func (m *Manager) sweepAndRearm() {
+	m.Sweep()
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if m.timer != nil {
+		m.timer = m.opts.Clock.AfterFunc(m.opts.SweepInterval, m.sweepAndRearm)
+	}
+}
+
+// managed returns the games of the manager, from the oldest to the newest
+func (m *Manager) managed() []*managedGame {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	r := make([]*managedGame, 0, len(m.games))
+	for _, mg := range m.games {
+		r = append(r, mg)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].created.Equal(r[j].created) {
+			return r[i].id < r[j].id
+		}
+		return r[i].created.Before(r[j].created)
+	})
+	return r
+}
+
+// summary returns the information about the game needed by a Manager
+func (g *Game) summary() summary {
+	o, _ := g.send(context.Background(), viewSummary{})
+	s, ok := o.(summary)
+	if !ok {
+		s.state = gameEnd // a closed game is as good as finished
+	}
+	return s
+}
+
+// randomID generates a random game ID
+func randomID() string {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		panic("crypto/rand is not available: " + err.Error())
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// randomCode generates a random join code
+func randomCode() string {
+	var b [codeLength]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		panic("crypto/rand is not available: " + err.Error())
+	}
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b[:])
+}
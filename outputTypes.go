@@ -23,10 +23,31 @@ type (
 	// The value associated with this type is the player's id
 	PlayerRegistered int8
 
+	// PlayerLeft is an Ok type.
+	// PlayerLeft means a player left the lobby, or was kicked out of it by the host.
+	// The players that sat after him have moved up by one seat
+	PlayerLeft struct {
+		Seat   int8   // Seat is the seat the player was sitting in
+		Player Player // Player is the identity of the player
+		Kicked bool   // Kicked tells if the player was kicked out
+		State  GameState
+	}
+
+	// ReadyChanged is an Ok type.
+	// ReadyChanged means a player told whether he is ready to start
+	ReadyChanged struct {
+		Seat  int8
+		Ready bool
+		State GameState
+	}
+
 	// GameStart is an Ok type.
 	// GameStart means the game has started.
-	// GameStart also carries a pointer to a GameState
-	GameStart GameState
+	// GameStart also carries a GameState, along with the Rules the game is played with
+	GameStart struct {
+		GameState
+		Rules Rules
+	}
 
 	// NextPresident is an Ok type.
 	// NextPresident that a round ended and a new president candidate was selected.
@@ -56,7 +77,8 @@ type (
 
 	// PolicyEnaction is an Ok type.
 	// PolicyEnaction means nobody won, but the Policy got enacted.
-	// If a special power has been activated, the specialPower field will let you know
+	// If a special power has been activated, the specialPower field will let you know.
+	// A power with nobody to use it on is skipped and reported as Nothing.
 	// PolicyEnaction also carries a pointer to a GameState
 	PolicyEnaction struct {
 		Enacted      Policy
@@ -66,15 +88,41 @@ type (
 
 	// SpecialPowerFeedback is an Ok type.
 	// SpecialPowerFeedback contains information about the power that has just been used.
-	// The Feedback field will carry a Policy slice in response to a Peek power, or a Role value in response to an Investigate power
+	// The Feedback field will carry a Policy slice in response to a Peek power, or a Party value in response to an Investigate power
 	SpecialPowerFeedback struct {
 		Feedback interface{}
 		State    GameState
 	}
 
-	// VetoRequest is an Ok type.
-	// VetoRequest means a veto is possible and the handler is now waiting for one or more VetoResponse inputs.
-	VetoRequest GameState
+	// VetoProposed is an Ok type.
+	// VetoProposed means the chancellor asked for a veto and the president has to answer it with a Vote.
+	// Hand carries the policies the chancellor holds, and is only shown to the government
+	VetoProposed struct {
+		Hand  []Policy
+		State GameState
+	}
+
+	// VetoAccepted is an Ok type.
+	// VetoAccepted means the president agreed to the veto: both policies were discarded and the election tracker advanced.
+	// If the tracker forced the enaction of a policy, Forced is true and Enacted is the enacted policy
+	VetoAccepted struct {
+		Forced  bool
+		Enacted Policy
+		State   GameState
+	}
+
+	// VetoRejected is an Ok type.
+	// VetoRejected means the president refused the veto and the chancellor has to enact one of his policies
+	VetoRejected GameState
+
+	// TimeoutApplied is an Ok type.
+	// TimeoutApplied means the deadline of a phase expired and the default actions were taken on behalf of the late players.
+	// Result is the Output the default actions produced
+	TimeoutApplied struct {
+		Players  []int8    // Players are the players who did not act in time
+		Commands []Command // Commands are the commands sent on behalf of the late players
+		Result   Output
+	}
 
 	// GameEnd is an Ok type.
 	// GameEnd means a condition to end the game has been met. the reason for the ending is in the field 'Why'
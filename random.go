@@ -0,0 +1,56 @@
+package SecretGopher
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+)
+
+// cryptoSource is a rand.Source64 that draws its values from crypto/rand.
+// It cannot be seeded, so games using it cannot be reproduced
+type cryptoSource struct{}
+
+// Seed does nothing, as crypto/rand cannot be seeded
+func (cryptoSource) Seed(int64) {}
+
+// Int63 returns a non-negative random 63-bit integer
+func (s cryptoSource) Int63() int64 {
+	return int64(s.Uint64() & (1<<63 - 1))
+}
+
+// Uint64 returns a random 64-bit integer
+func (cryptoSource) Uint64() uint64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		panic("crypto/rand is not available: " + err.Error())
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// countingSource is a rand.Source that counts the values drawn from it.
+// A seeded countingSource can be brought back to any point of its sequence, which makes the games using it restorable
+type countingSource struct {
+	src   rand.Source
+	calls uint64 // calls is the number of values drawn from src
+}
+
+// newCountingSource returns a countingSource seeded with seed that already drew calls values
+func newCountingSource(seed int64, calls uint64) *countingSource {
+	s := &countingSource{src: rand.NewSource(seed)}
+	for s.calls < calls {
+		s.Int63()
+	}
+	return s
+}
+
+// Seed reseeds the source and resets its counter
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.calls = 0
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer
+func (s *countingSource) Int63() int64 {
+	s.calls++
+	return s.src.Int63()
+}
@@ -0,0 +1,100 @@
+package SecretGopher
+
+import (
+	"context"
+	"fmt"
+)
+
+// ResultKind enumerates the Ok types a game can reply with
+type ResultKind uint8
+
+const (
+	UnknownResult               ResultKind = iota // UnknownResult is never returned by Result.Kind
+	VoteRegisteredResult                          // VoteRegisteredResult is the kind of VoteRegistered
+	PlayerRegisteredResult                        // PlayerRegisteredResult is the kind of PlayerRegistered
+	PlayerLeftResult                              // PlayerLeftResult is the kind of PlayerLeft
+	ReadyChangedResult                            // ReadyChangedResult is the kind of ReadyChanged
+	GameStartResult                               // GameStartResult is the kind of GameStart
+	NextPresidentResult                           // NextPresidentResult is the kind of NextPresident
+	ElectionStartResult                           // ElectionStartResult is the kind of ElectionStart
+	LegislationPresidentResult                    // LegislationPresidentResult is the kind of LegislationPresident
+	LegislationChancellorResult                   // LegislationChancellorResult is the kind of LegislationChancellor
+	PolicyEnactionResult                          // PolicyEnactionResult is the kind of PolicyEnaction
+	SpecialPowerFeedbackResult                    // SpecialPowerFeedbackResult is the kind of SpecialPowerFeedback
+	VetoProposedResult                            // VetoProposedResult is the kind of VetoProposed
+	VetoAcceptedResult                            // VetoAcceptedResult is the kind of VetoAccepted
+	VetoRejectedResult                            // VetoRejectedResult is the kind of VetoRejected
+	TimeoutAppliedResult                          // TimeoutAppliedResult is the kind of TimeoutApplied
+	GameEndResult                                 // GameEndResult is the kind of GameEnd
+)
+
+// Result is implemented by every Ok type, and only by them.
+// Switching on Kind, or on the type of the Result, covers every way a game can accept a command
+type Result interface {
+	Kind() ResultKind
+	result() // result seals the interface
+}
+
+func (VoteRegistered) Kind() ResultKind        { return VoteRegisteredResult }
+func (PlayerRegistered) Kind() ResultKind      { return PlayerRegisteredResult }
+func (PlayerLeft) Kind() ResultKind            { return PlayerLeftResult }
+func (ReadyChanged) Kind() ResultKind          { return ReadyChangedResult }
+func (GameStart) Kind() ResultKind             { return GameStartResult }
+func (NextPresident) Kind() ResultKind         { return NextPresidentResult }
+func (ElectionStart) Kind() ResultKind         { return ElectionStartResult }
+func (LegislationPresident) Kind() ResultKind  { return LegislationPresidentResult }
+func (LegislationChancellor) Kind() ResultKind { return LegislationChancellorResult }
+func (PolicyEnaction) Kind() ResultKind        { return PolicyEnactionResult }
+func (SpecialPowerFeedback) Kind() ResultKind  { return SpecialPowerFeedbackResult }
+func (VetoProposed) Kind() ResultKind          { return VetoProposedResult }
+func (VetoAccepted) Kind() ResultKind          { return VetoAcceptedResult }
+func (VetoRejected) Kind() ResultKind          { return VetoRejectedResult }
+func (TimeoutApplied) Kind() ResultKind        { return TimeoutAppliedResult }
+func (GameEnd) Kind() ResultKind               { return GameEndResult }
+
+func (VoteRegistered) result()        {}
+func (PlayerRegistered) result()      {}
+func (PlayerLeft) result()            {}
+func (ReadyChanged) result()          {}
+func (GameStart) result()             {}
+func (NextPresident) result()         {}
+func (ElectionStart) result()         {}
+func (LegislationPresident) result()  {}
+func (LegislationChancellor) result() {}
+func (PolicyEnaction) result()        {}
+func (SpecialPowerFeedback) result()  {}
+func (VetoProposed) result()          {}
+func (VetoAccepted) result()          {}
+func (VetoRejected) result()          {}
+func (TimeoutApplied) result()        {}
+func (GameEnd) result()               {}
+
+// Unpack turns an Output into its Result, or into the Error that rejected the command.
+// The error returned for an Error output is the Error itself, so errors.Is and errors.As can look into it
+func Unpack(o Output) (Result, error) {
+	switch o := o.(type) {
+	case Ok:
+		if r, ok := o.Info.(Result); ok {
+			return r, nil
+		}
+		return nil, fmt.Errorf("SecretGopher: unexpected result %T", o.Info)
+	case Error:
+		return nil, o
+	}
+	return nil, fmt.Errorf("SecretGopher: unexpected output %T", o)
+}
+
+// Apply sends command c to the game like Do, and unpacks its Output.
+// The error is either the Error that rejected the command or the error of ctx
+func (g *Game) Apply(ctx context.Context, c Command) (Result, error) {
+	o, err := g.Do(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return Unpack(o)
+}
+
+// Outcome returns the Result of the Output the default actions produced
+func (t TimeoutApplied) Outcome() (Result, error) {
+	return Unpack(t.Result)
+}
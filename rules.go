@@ -0,0 +1,352 @@
+package SecretGopher
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Rules are the rules a game is played with. House rules can change any of them, as long as they pass Validate.
+// Roles, policies and win conditions are all described by data, so that expansions with more parties can be played
+type Rules struct {
+	MinPlayers     int8           // MinPlayers is the number of players needed to start the game
+	MaxPlayers     int8           // MaxPlayers is the number of players the game can hold
+	Roles          []RoleRules    // Roles[r] describes the role r
+	Deck           []int8         // Deck[p] is the number of policies p in the deck, every kind of policy has its own track
+	Wins           []WinCondition // Wins are the ways the game can end, checked in order
+	VetoUnlock     int8           // VetoUnlock is the number of fascist policies that unlocks the veto power, zero disables the veto
+	ChaosThreshold int8           // ChaosThreshold is the number of failed governments in a row that forces the top policy
+	Tables         []Table        // Tables holds the setup for every number of players, from MinPlayers to MaxPlayers
+}
+
+// RoleRules describe a role
+type RoleRules struct {
+	Party     Party  // Party is the party the role is a member of, which is what an investigation reveals
+	Knows     []Role // Knows are the roles that the players with this role can recognize at the table
+	KnowsUpTo int8   // KnowsUpTo, if not zero, restricts Knows to the tables of at most KnowsUpTo players
+}
+
+// WinKind enumerates the kinds of win conditions
+type WinKind int8
+
+const (
+	TrackFilled       WinKind = iota // TrackFilled is met when Count policies of kind Policy are enacted
+	ChancellorElected                // ChancellorElected is met when a player with Role is elected chancellor once Count policies of kind Policy are enacted
+	PlayerExecuted                   // PlayerExecuted is met when a player with Role is executed
+)
+
+// WinCondition is a way the game can end
+type WinCondition struct {
+	Kind   WinKind
+	Policy Policy     // Policy is the kind of policy the condition counts
+	Count  int8       // Count is the number of policies the condition needs
+	Role   Role       // Role is the role the condition is about
+	Ending GameEnding // Ending is how the game ends when the condition is met
+}
+
+// Table is the setup of a game played by a given number of players
+type Table struct {
+	Players int8              // Players is the number of players the setup is for
+	Roles   []int8            // Roles[r] is the number of players dealt the role r. LiberalParty is dealt to the seats left
+	Powers  [][]SpecialPowers // Powers[p][i] is the power granted by the enaction of the (i+1)th policy p, missing powers mean Nothing
+	Deck    []int8            // Deck, if not nil, replaces the Deck of the Rules for this number of players
+}
+
+// OfficialRules returns the rules of the board game
+func OfficialRules() Rules {
+	small := []SpecialPowers{Nothing, Nothing, Peek, Execution, Execution}
+	medium := []SpecialPowers{Nothing, Investigate, Election, Execution, Execution}
+	large := []SpecialPowers{Investigate, Investigate, Election, Execution, Execution}
+	return Rules{
+		MinPlayers: 5,
+		MaxPlayers: 10,
+		Roles: []RoleRules{
+			LiberalParty: {Party: LiberalMembership},
+			FascistParty: {Party: FascistMembership, Knows: []Role{FascistParty, Hitler}},
+			Hitler:       {Party: FascistMembership, Knows: []Role{FascistParty}, KnowsUpTo: 6},
+		},
+		Deck: []int8{LiberalPolicy: 6, FascistPolicy: 11},
+		Wins: []WinCondition{
+			{Kind: TrackFilled, Policy: LiberalPolicy, Count: 5, Ending: LiberalPolicyWin},
+			{Kind: TrackFilled, Policy: FascistPolicy, Count: 6, Ending: FascistPolicyWin},
+			{Kind: PlayerExecuted, Role: Hitler, Ending: LiberalExecutionWin},
+			{Kind: ChancellorElected, Role: Hitler, Policy: FascistPolicy, Count: 3, Ending: FascistElectionWin},
+		},
+		VetoUnlock:     5,
+		ChaosThreshold: 3,
+		Tables: []Table{
+			{Players: 5, Roles: []int8{FascistParty: 1, Hitler: 1}, Powers: [][]SpecialPowers{FascistPolicy: small}},
+			{Players: 6, Roles: []int8{FascistParty: 1, Hitler: 1}, Powers: [][]SpecialPowers{FascistPolicy: small}},
+			{Players: 7, Roles: []int8{FascistParty: 2, Hitler: 1}, Powers: [][]SpecialPowers{FascistPolicy: medium}},
+			{Players: 8, Roles: []int8{FascistParty: 2, Hitler: 1}, Powers: [][]SpecialPowers{FascistPolicy: medium}},
+			{Players: 9, Roles: []int8{FascistParty: 3, Hitler: 1}, Powers: [][]SpecialPowers{FascistPolicy: large}},
+			{Players: 10, Roles: []int8{FascistParty: 3, Hitler: 1}, Powers: [][]SpecialPowers{FascistPolicy: large}},
+		},
+	}
+}
+
+// LargeTableRules returns the rules of the board game extended to tables of up to 16 players.
+// The larger tables keep dealing one more fascist every two players, grant the powers of the ten players table
+// and play with a larger deck
+func LargeTableRules() Rules {
+	r := OfficialRules()
+	r.MaxPlayers = 16
+	powers := r.Tables[len(r.Tables)-1].Powers
+	for n := int8(11); n <= r.MaxPlayers; n++ {
+		r.Tables = append(r.Tables, Table{
+			Players: n,
+			Roles:   []int8{FascistParty: (n - 3) / 2, Hitler: 1},
+			Powers:  powers,
+			Deck:    []int8{LiberalPolicy: 8, FascistPolicy: 14},
+		})
+	}
+	return r
+}
+
+// maxSeats is the number of seats a game can hold at most, so that the seat arithmetic fits in an int8
+const maxSeats = 64
+
+// Validate tells if the rules make for a playable game, returning the first problem found
+func (r Rules) Validate() error {
+	switch {
+	case r.MinPlayers < 3:
+		return errors.New("SecretGopher: a game needs at least 3 players")
+	case r.MaxPlayers < r.MinPlayers || r.MaxPlayers > maxSeats:
+		return fmt.Errorf("SecretGopher: invalid maximum number of players %d", r.MaxPlayers)
+	case len(r.Roles) == 0:
+		return errors.New("SecretGopher: there must be at least the liberal role")
+	case len(r.Wins) == 0:
+		return errors.New("SecretGopher: there must be a way to win the game")
+	case r.VetoUnlock < 0 || r.VetoUnlock > 0 && int(FascistPolicy) >= len(r.Deck):
+		return fmt.Errorf("SecretGopher: invalid veto unlock %d", r.VetoUnlock)
+	case r.ChaosThreshold < 1:
+		return fmt.Errorf("SecretGopher: invalid chaos threshold %d", r.ChaosThreshold)
+	case len(r.Tables) != int(r.MaxPlayers-r.MinPlayers)+1:
+		return errors.New("SecretGopher: there must be a table for every number of players")
+	}
+	if err := r.validDeck(r.Deck); err != nil {
+		return err
+	}
+	for i, role := range r.Roles {
+		for _, k := range role.Knows {
+			if !r.validRole(k) {
+				return fmt.Errorf("SecretGopher: role %d knows the unknown role %d", i, k)
+			}
+		}
+	}
+	for _, w := range r.Wins {
+		switch {
+		case w.Ending <= StillRunning:
+			return fmt.Errorf("SecretGopher: invalid game ending %d", w.Ending)
+		case w.Kind != PlayerExecuted && (w.Policy < 0 || int(w.Policy) >= len(r.Deck)):
+			return fmt.Errorf("SecretGopher: win condition on the unknown policy %d", w.Policy)
+		case w.Kind == TrackFilled && w.Count < 1:
+			return fmt.Errorf("SecretGopher: invalid track length for policy %d", w.Policy)
+		case w.Kind != TrackFilled && !r.validRole(w.Role):
+			return fmt.Errorf("SecretGopher: win condition on the unknown role %d", w.Role)
+		case w.Kind < TrackFilled || w.Kind > PlayerExecuted:
+			return fmt.Errorf("SecretGopher: unknown win condition %d", w.Kind)
+		}
+	}
+	for i, t := range r.Tables {
+		if t.Players != r.MinPlayers+int8(i) {
+			return fmt.Errorf("SecretGopher: table %d is for %d players, expected %d", i, t.Players, r.MinPlayers+int8(i))
+		}
+		if len(t.Roles) > len(r.Roles) {
+			return fmt.Errorf("SecretGopher: unknown roles dealt to %d players", t.Players)
+		}
+		dealt := 0
+		for role, n := range t.Roles {
+			if n < 0 {
+				return fmt.Errorf("SecretGopher: invalid roles for %d players", t.Players)
+			}
+			if role != int(LiberalParty) {
+				dealt += int(n)
+			}
+		}
+		if dealt > int(t.Players) {
+			return fmt.Errorf("SecretGopher: too many roles dealt to %d players", t.Players)
+		}
+		if t.Deck != nil {
+			if err := r.validDeck(t.Deck); err != nil {
+				return fmt.Errorf("%v with %d players", err, t.Players)
+			}
+		}
+		if len(t.Powers) > len(r.Deck) {
+			return fmt.Errorf("SecretGopher: powers for unknown policies with %d players", t.Players)
+		}
+		for _, track := range t.Powers {
+			for _, p := range track {
+				if p < Nothing || p > Execution {
+					return fmt.Errorf("SecretGopher: unknown power %d for %d players", p, t.Players)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validDeck checks that deck holds every kind of policy of the rules, in a number that can fill their tracks
+// while leaving three policies to draw however full the tracks are
+func (r Rules) validDeck(deck []int8) error {
+	if len(deck) != len(r.Deck) {
+		return errors.New("SecretGopher: the deck does not hold every kind of policy")
+	}
+	cards := 0
+	for p, c := range deck {
+		if c < 0 {
+			return fmt.Errorf("SecretGopher: invalid number of policies %d", p)
+		}
+		cards += int(c)
+	}
+	if cards > 127 {
+		return fmt.Errorf("SecretGopher: invalid deck of %d policies", cards)
+	}
+	// the tracks can hold up to one policy less than they need to win while the game runs,
+	// and the policies left must still be enough for a president to draw
+	onBoards := 0
+	for _, w := range r.Wins {
+		if w.Kind != TrackFilled || int(w.Policy) >= len(deck) {
+			continue
+		}
+		if w.Count > deck[w.Policy] {
+			return fmt.Errorf("SecretGopher: the deck cannot fill the track of policy %d", w.Policy)
+		}
+		onBoards += int(w.Count) - 1
+	}
+	if cards < onBoards+3 {
+		return fmt.Errorf("SecretGopher: invalid deck of %d policies, at least %d are needed", cards, onBoards+3)
+	}
+	return nil
+}
+
+// validRole tells if the rules describe role
+func (r Rules) validRole(role Role) bool {
+	return role >= 0 && int(role) < len(r.Roles)
+}
+
+// PartyOf returns the party the role is a member of
+func (r Rules) PartyOf(role Role) Party {
+	if !r.validRole(role) {
+		return UnknownParty
+	}
+	return r.Roles[role].Party
+}
+
+// clone returns a deep copy of r, so that a game does not share its rules with the caller
+func (r Rules) clone() Rules {
+	c := r
+	c.Roles = make([]RoleRules, len(r.Roles))
+	for i, role := range r.Roles {
+		role.Knows = append([]Role(nil), role.Knows...)
+		c.Roles[i] = role
+	}
+	c.Deck = append([]int8(nil), r.Deck...)
+	c.Wins = append([]WinCondition(nil), r.Wins...)
+	c.Tables = make([]Table, len(r.Tables))
+	for i, t := range r.Tables {
+		t.Roles = append([]int8(nil), t.Roles...)
+		t.Deck = append([]int8(nil), t.Deck...)
+		if t.Powers != nil {
+			powers := make([][]SpecialPowers, len(t.Powers))
+			for p, track := range t.Powers {
+				powers[p] = append([]SpecialPowers(nil), track...)
+			}
+			t.Powers = powers
+		}
+		c.Tables[i] = t
+	}
+	return c
+}
+
+// orOfficial returns r, or OfficialRules if r was left empty
+func (r Rules) orOfficial() Rules {
+	if r.MaxPlayers == 0 && len(r.Tables) == 0 {
+		return OfficialRules()
+	}
+	return r
+}
+
+// table returns the setup for the current number of players
+func (g *gameData) table() Table {
+	return g.rules.Tables[g.players-g.rules.MinPlayers]
+}
+
+// policies returns the number of policies of every kind dealt at the current table
+func (g *gameData) policies() []int8 {
+	if d := g.table().Deck; d != nil {
+		return d
+	}
+	return g.rules.Deck
+}
+
+// track returns the number of policies p enacted
+func (g *gameData) track(p Policy) int8 {
+	if p < 0 || int(p) >= len(g.tracks) {
+		return 0
+	}
+	return g.tracks[p]
+}
+
+// power returns the power granted by the enaction of the last policy p
+func (g *gameData) power(p Policy) SpecialPowers {
+	t := g.table()
+	if int(p) >= len(t.Powers) || int(g.track(p)) > len(t.Powers[p]) {
+		return Nothing
+	}
+	return t.Powers[p][g.track(p)-1]
+}
+
+// knows tells if viewer can recognize the role of target at the table
+func (g *gameData) knows(viewer, target int8) bool {
+	r := g.rules.Roles[g.roles[viewer]]
+	if r.KnowsUpTo != 0 && g.players > r.KnowsUpTo {
+		return false
+	}
+	for _, k := range r.Knows {
+		if g.roles[target] == k {
+			return true
+		}
+	}
+	return false
+}
+
+// dealRoles deals the roles of the current table, starting from the last role.
+// Every seat left gets the first role
+func (g *gameData) dealRoles() {
+	g.roles = make([]Role, g.players)
+	t := g.table()
+	for r := len(t.Roles) - 1; r > 0; r-- {
+		// extract a player
+		// if the player has no role yet, deal him r and increase the counter
+		for i := int8(0); i < t.Roles[r]; {
+			if p := g.rng.Intn(int(g.players)); g.roles[p] == LiberalParty {
+				g.roles[p] = Role(r)
+				i++
+			}
+		}
+	}
+}
+
+// gameOver returns how the game ended, or StillRunning if no win condition is met
+func (g *gameData) gameOver() GameEnding {
+	for _, w := range g.rules.Wins {
+		switch w.Kind {
+		case TrackFilled:
+			if g.track(w.Policy) >= w.Count {
+				return w.Ending
+			}
+		case ChancellorElected:
+			// the chancellor only wins the game at the moment he is elected
+			if g.state == governmentElection && g.roles[g.chancellor] == w.Role && g.track(w.Policy) >= w.Count {
+				return w.Ending
+			}
+		case PlayerExecuted:
+			for _, p := range g.killed {
+				if g.roles[p] == w.Role {
+					return w.Ending
+				}
+			}
+		}
+	}
+	return StillRunning
+}
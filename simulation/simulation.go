@@ -0,0 +1,273 @@
+// Package simulation plays batches of headless SecretGopher games between bots, and reports how they went.
+// Games are seeded one after the other starting from Config.Seed, so a batch played twice gives the same Report
+package simulation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	SG "github.com/nylone/SecretGopher"
+)
+
+// BotFactory creates the bot playing seat for party in the game seeded with seed
+type BotFactory func(seat int8, party SG.Party, seed int64) SG.Bot
+
+// Random creates RandomBots
+func Random() BotFactory {
+	return func(_ int8, _ SG.Party, seed int64) SG.Bot { return SG.NewRandomBot(seed) }
+}
+
+// Strategy creates StrategyBots playing at level
+func Strategy(level SG.Difficulty) BotFactory {
+	return func(_ int8, _ SG.Party, seed int64) SG.Bot { return SG.NewStrategyBot(seed, level) }
+}
+
+// Teams lets liberals create the bots of the liberals, and others create the bots of every other party
+func Teams(liberals, others BotFactory) BotFactory {
+	return func(seat int8, party SG.Party, seed int64) SG.Bot {
+		if party == SG.LiberalMembership {
+			return liberals(seat, party, seed)
+		}
+		return others(seat, party, seed)
+	}
+}
+
+// Config describes a batch of games
+type Config struct {
+	Games   int        // Games is the number of games to play
+	Players int8       // Players is the number of players at every table
+	Rules   SG.Rules   // Rules are the rules of the games. Empty Rules mean SG.OfficialRules
+	Seed    int64      // Seed is the seed of the first game, the game i is seeded with Seed+i
+	Workers int        // Workers is the number of games played at once. Zero means runtime.GOMAXPROCS(0)
+	Bots    BotFactory // Bots creates the bots. A nil Bots means Random
+}
+
+// Report is the outcome of a batch of games
+type Report struct {
+	Games    int                          // Games is the number of games played
+	Endings  map[SG.GameEnding]int        // Endings counts the games by the way they ended
+	Wins     map[SG.Party]int             // Wins counts the games won by every party
+	Rounds   float64                      // Rounds is the average number of chancellor nominations in a game
+	Policies float64                      // Policies is the average number of policies enacted in a game
+	Vetoes   float64                      // Vetoes is the average number of vetoes accepted in a game
+	Powers   map[SG.SpecialPowers]float64 // Powers is the average number of times every power was used in a game
+	Seats    []Seat                       // Seats are the results of every seat
+}
+
+// Seat is the record of a seat across a batch of games
+type Seat struct {
+	Played   map[SG.Party]int // Played counts the games the seat played for every party
+	Won      map[SG.Party]int // Won counts the games the seat won for every party
+	Executed int              // Executed is the number of games in which the seat was executed
+}
+
+// WinRate returns the share of games won by party
+func (r Report) WinRate(party SG.Party) float64 {
+	if r.Games == 0 {
+		return 0
+	}
+	return float64(r.Wins[party]) / float64(r.Games)
+}
+
+// WinRate returns the share of the games played for party that the seat won
+func (s Seat) WinRate(party SG.Party) float64 {
+	if s.Played[party] == 0 {
+		return 0
+	}
+	return float64(s.Won[party]) / float64(s.Played[party])
+}
+
+// game is the outcome of a single game
+type game struct {
+	end     SG.GameEnd
+	entries []SG.LogEntry
+}
+
+// Run plays the batch of games described by c, spreading them over c.Workers goroutines.
+// The games are attached to the handler pool like any other game, and closed once they end
+func Run(ctx context.Context, c Config) (Report, error) {
+	if c.Rules.MaxPlayers == 0 && len(c.Rules.Tables) == 0 {
+		c.Rules = SG.OfficialRules()
+	}
+	if err := c.Rules.Validate(); err != nil {
+		return Report{}, err
+	}
+	if c.Players < c.Rules.MinPlayers || c.Players > c.Rules.MaxPlayers {
+		return Report{}, fmt.Errorf("simulation: the rules do not allow %d players", c.Players)
+	}
+	if c.Games < 0 {
+		return Report{}, errors.New("simulation: negative number of games")
+	}
+	if c.Bots == nil {
+		c.Bots = Random()
+	}
+	if c.Workers <= 0 {
+		c.Workers = runtime.GOMAXPROCS(0)
+	}
+
+	games := make([]game, c.Games)
+	jobs := make(chan int)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	for w := 0; w < c.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				g, err := play(ctx, c, c.Seed+int64(i))
+				if err != nil {
+					once.Do(func() {
+						first = fmt.Errorf("simulation: game %d: %w", i, err)
+						cancel()
+					})
+					continue
+				}
+				games[i] = g
+			}
+		}()
+	}
+feed:
+	for i := range games {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if first != nil {
+		return Report{}, first
+	}
+	if err := ctx.Err(); err != nil {
+		return Report{}, err
+	}
+	return summarize(games, c.Players), nil
+}
+
+// play plays the game seeded with seed
+func play(ctx context.Context, c Config, seed int64) (game, error) {
+	G := SG.NewGameWithOptions(SG.Options{Seed: seed, Rules: c.Rules})
+	defer G.Close()
+	for p := int8(0); p < c.Players; p++ {
+		G.AddPlayer()
+	}
+	if _, err := SG.Unpack(G.Start()); err != nil {
+		return game{}, err
+	}
+	bots := make(map[int8]SG.Bot, c.Players)
+	for p := int8(0); p < c.Players; p++ {
+		bots[p] = c.Bots(p, G.StateFor(p).Parties[p], seed<<6+int64(p))
+	}
+	end, err := SG.NewDriver(&G, bots).Run(ctx)
+	if err != nil {
+		return game{}, err
+	}
+	return game{end: end, entries: G.Log().Entries}, nil
+}
+
+// summarize adds up the outcomes of the games
+func summarize(games []game, players int8) Report {
+	r := Report{
+		Games:   len(games),
+		Endings: map[SG.GameEnding]int{},
+		Wins:    map[SG.Party]int{},
+		Powers:  map[SG.SpecialPowers]float64{},
+		Seats:   make([]Seat, players),
+	}
+	for i := range r.Seats {
+		r.Seats[i] = Seat{Played: map[SG.Party]int{}, Won: map[SG.Party]int{}}
+	}
+	for _, g := range games {
+		winner := g.end.Why.Winner()
+		r.Endings[g.end.Why]++
+		r.Wins[winner]++
+		for _, n := range g.end.State.Tracks {
+			r.Policies += float64(n)
+		}
+		for _, e := range g.entries {
+			switch e.Command.Kind {
+			case SG.MakeChancellorCommand:
+				r.Rounds++
+			case SG.SpecialPowerCommand:
+				r.Powers[e.Command.Power]++
+			}
+			if res, _ := SG.Unpack(e.Output); res != nil && res.Kind() == SG.VetoAcceptedResult {
+				r.Vetoes++
+			}
+		}
+		for p, party := range g.end.State.Parties {
+			r.Seats[p].Played[party]++
+			if party == winner {
+				r.Seats[p].Won[party]++
+			}
+		}
+		for _, p := range g.end.State.Killed {
+			r.Seats[p].Executed++
+		}
+	}
+	if r.Games > 0 {
+		n := float64(r.Games)
+		r.Rounds /= n
+		r.Policies /= n
+		r.Vetoes /= n
+		for p := range r.Powers {
+			r.Powers[p] /= n
+		}
+	}
+	return r
+}
+
+// String formats the report as a plain text table
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "games\t%d\n", r.Games)
+	fmt.Fprintf(&b, "rounds\t%.2f\npolicies\t%.2f\nvetoes\t%.2f\n", r.Rounds, r.Policies, r.Vetoes)
+
+	endings := make([]SG.GameEnding, 0, len(r.Endings))
+	for e := range r.Endings {
+		endings = append(endings, e)
+	}
+	sort.Slice(endings, func(i, j int) bool { return endings[i] < endings[j] })
+	b.WriteString("\nending\tgames\trate\n")
+	for _, e := range endings {
+		fmt.Fprintf(&b, "%v\t%d\t%.3f\n", e, r.Endings[e], float64(r.Endings[e])/float64(r.Games))
+	}
+
+	powers := make([]SG.SpecialPowers, 0, len(r.Powers))
+	for p := range r.Powers {
+		powers = append(powers, p)
+	}
+	sort.Slice(powers, func(i, j int) bool { return powers[i] < powers[j] })
+	b.WriteString("\npower\tper game\n")
+	for _, p := range powers {
+		fmt.Fprintf(&b, "%v\t%.2f\n", p, r.Powers[p])
+	}
+
+	b.WriteString("\nseat\tliberal win rate\tother win rate\texecuted\n")
+	for i, s := range r.Seats {
+		played, won := 0, 0
+		for p, n := range s.Played {
+			if p != SG.LiberalMembership {
+				played += n
+				won += s.Won[p]
+			}
+		}
+		other := 0.0
+		if played > 0 {
+			other = float64(won) / float64(played)
+		}
+		fmt.Fprintf(&b, "%d\t%.3f\t%.3f\t%d\n", i, s.WinRate(SG.LiberalMembership), other, s.Executed)
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package simulation
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	SG "github.com/nylone/SecretGopher"
+)
+
+func TestRun(t *testing.T) {
+	c := Config{Games: 60, Players: 7, Seed: 25, Workers: 1, Bots: Teams(Strategy(SG.Hard), Random())}
+	r, err := Run(context.Background(), c)
+	if err != nil {
+		t.Fatal("Run failed:", err)
+	}
+	// the report does not depend on how the games were spread over the workers
+	c.Workers = 8
+	if p, _ := Run(context.Background(), c); !reflect.DeepEqual(p, r) {
+		t.Error("The same seeds gave different reports")
+	}
+
+	ended, won := 0, 0
+	for e, n := range r.Endings {
+		if e == SG.StillRunning {
+			t.Error("A game did not end")
+		}
+		ended += n
+	}
+	for _, n := range r.Wins {
+		won += n
+	}
+	if r.Games != 60 || ended != 60 || won != 60 {
+		t.Error("Expected 60 games, got", r.Games, ended, won)
+	}
+	if r.WinRate(SG.LiberalMembership) <= 0.5 {
+		t.Error("Hard liberals should beat random fascists, they won", r.WinRate(SG.LiberalMembership))
+	}
+	if r.Rounds < 5 || r.Policies < 5 {
+		t.Error("The games were too short:", r.Rounds, r.Policies)
+	}
+	for i, s := range r.Seats {
+		if s.Played[SG.LiberalMembership]+s.Played[SG.FascistMembership] != 60 {
+			t.Error("Seat", i, "did not play every game:", s.Played)
+		}
+	}
+	if r.Powers[SG.Investigate] == 0 {
+		t.Error("Expected investigations at 7 players")
+	}
+
+	if _, err := Run(context.Background(), Config{Games: 1, Players: 11}); err == nil {
+		t.Error("The official rules do not allow 11 players")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, Config{Games: 10, Players: 5}); err == nil {
+		t.Error("A cancelled context should stop the games")
+	}
+}
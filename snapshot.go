@@ -0,0 +1,298 @@
+package SecretGopher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// snapshotVersion is the version of the snapshot schema produced by Game.Snapshot.
+// It must be increased every time the schema changes in a way older versions cannot read
+const snapshotVersion = 1
+
+// snapshot is the serializable copy of the whole internal state of a game
+type snapshot struct {
+	Version       int
+	Seed          int64  // Seed is the seed of the game, meaningful only if Seeded
+	Seeded        bool   // Seeded tells if the game random source was created from Seed
+	RandomCalls   uint64 // RandomCalls is the number of values the seeded random source already produced
+	CryptoRand    bool   // CryptoRand tells if the game draws its randomness from crypto/rand
+	State         state
+	Players       int8
+	Pile          []Policy
+	Discarded     []Policy
+	Enacted       []Policy
+	President     int8
+	Chancellor    int8
+	Roles         []Role
+	NextPresident int8
+	OldGov        []int8
+	Investigated  []int8
+	Investigators []int8
+	Votes         []Vote
+	Voted         int8
+	Killed        []int8
+	PolicyChoice  []Policy
+	VetoDenied    bool
+	ETracker      int8
+	Tracks        []int8
+	Log           []LogEntry
+	Timeouts      Timeouts
+	Rules         Rules
+	Identities    []Player
+	Host          int8
+	Ready         []bool
+	AutoStart     bool
+}
+
+// snapshot copies the internal state of the game
+func (g *gameData) snapshot() snapshot {
+	s := snapshot{
+		Version:       snapshotVersion,
+		Seed:          g.seed,
+		Seeded:        g.seeded,
+		CryptoRand:    g.crypto,
+		State:         g.state,
+		Players:       g.players,
+		Pile:          append([]Policy{}, g.deck.pile...),
+		Discarded:     append([]Policy{}, g.deck.discarded...),
+		Enacted:       append([]Policy{}, g.deck.enacted...),
+		President:     g.president,
+		Chancellor:    g.chancellor,
+		Roles:         append([]Role{}, g.roles...),
+		NextPresident: g.nextPresident,
+		OldGov:        append([]int8{}, g.oldGov...),
+		Investigated:  append([]int8{}, g.investigated...),
+		Investigators: append([]int8{}, g.investigators...),
+		Votes:         append([]Vote{}, g.votes...),
+		Voted:         g.voted,
+		Killed:        append([]int8{}, g.killed...),
+		PolicyChoice:  append([]Policy{}, g.policyChoice...),
+		VetoDenied:    g.vetoDenied,
+		ETracker:      g.eTracker,
+		Tracks:        append([]int8{}, g.tracks...),
+		Log:           append([]LogEntry{}, g.log...),
+		Timeouts:      g.timeouts,
+		Rules:         g.rules.clone(),
+		Identities:    append([]Player{}, g.identities...),
+		Host:          g.host,
+		Ready:         append([]bool{}, g.ready...),
+		AutoStart:     g.autoStart,
+	}
+	if g.source != nil {
+		s.RandomCalls = g.source.calls
+	}
+	return s
+}
+
+// check makes sure the snapshot describes a consistent game
+func (s *snapshot) check() error {
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("SecretGopher: unsupported snapshot version %d", s.Version)
+	}
+	if s.State != waitingPlayers && len(s.Tracks) != len(s.Rules.Deck) {
+		return errors.New("SecretGopher: the tracks do not match the deck")
+	}
+	if s.State > gameEnd {
+		return fmt.Errorf("SecretGopher: unknown game state %d", s.State)
+	}
+	if err := s.Rules.Validate(); err != nil {
+		return err
+	}
+	if s.Players < 0 || s.Players > s.Rules.MaxPlayers {
+		return fmt.Errorf("SecretGopher: invalid number of players %d", s.Players)
+	}
+	if s.State != waitingPlayers && (len(s.Roles) != int(s.Players) || len(s.Votes) != int(s.Players)) {
+		return errors.New("SecretGopher: roles and votes do not match the number of players")
+	}
+	if len(s.Identities) != int(s.Players) || len(s.Ready) != int(s.Players) {
+		return errors.New("SecretGopher: identities do not match the number of players")
+	}
+	if s.Host < NotSet || s.Host >= s.Players {
+		return fmt.Errorf("SecretGopher: invalid host %d", s.Host)
+	}
+	if len(s.OldGov) != 2 {
+		return errors.New("SecretGopher: invalid term limits")
+	}
+	if len(s.Investigated) != len(s.Investigators) {
+		return errors.New("SecretGopher: investigations do not match their investigators")
+	}
+	if s.State == waitingPlayers {
+		return nil
+	}
+	// a running game is indexed by its seats, roles and policies: they must all be in range
+	if s.Players < s.Rules.MinPlayers {
+		return fmt.Errorf("SecretGopher: invalid number of players %d", s.Players)
+	}
+	for _, r := range s.Roles {
+		if !s.Rules.validRole(r) {
+			return fmt.Errorf("SecretGopher: unknown role %d", r)
+		}
+	}
+	if !s.seats(false, s.President) || !s.seats(true, s.Chancellor, s.NextPresident) || !s.seats(true, s.OldGov...) ||
+		!s.seats(false, s.Killed...) || !s.seats(false, s.Investigated...) || !s.seats(false, s.Investigators...) {
+		return errors.New("SecretGopher: invalid seat")
+	}
+	for _, v := range s.Votes {
+		if v < NoVote || v > Nein {
+			return fmt.Errorf("SecretGopher: invalid vote %d", v)
+		}
+	}
+	voted := int8(0)
+	for _, v := range s.Votes {
+		if v != NoVote {
+			voted++
+		}
+	}
+	if s.Voted != voted {
+		return fmt.Errorf("SecretGopher: invalid number of votes %d", s.Voted)
+	}
+	// the government holds the hand of the legislative session, the other states keep the last one for reference
+	held := 0
+	switch s.State {
+	case presidentLegislation:
+		held = 3
+	case chancellorLegislation, vetoPresident:
+		held = 2
+	}
+	if held != 0 && len(s.PolicyChoice) != held {
+		return fmt.Errorf("SecretGopher: invalid hand of %d policies", len(s.PolicyChoice))
+	}
+	if len(s.Pile)+len(s.Discarded)+held < 3 {
+		return errors.New("SecretGopher: too few policies left to draw")
+	}
+	// every policy dealt is in a pile, on the boards or in the hand of the government
+	dealt := make([]int, len(s.Rules.Deck))
+	for _, pile := range [][]Policy{s.Pile, s.Discarded, s.Enacted, s.PolicyChoice[:held]} {
+		for _, p := range pile {
+			if p < 0 || int(p) >= len(s.Rules.Deck) {
+				return fmt.Errorf("SecretGopher: unknown policy %d", p)
+			}
+			dealt[p]++
+		}
+	}
+	deck := s.Rules.Tables[s.Players-s.Rules.MinPlayers].Deck
+	if deck == nil {
+		deck = s.Rules.Deck
+	}
+	for p, n := range deck {
+		if dealt[p] != int(n) {
+			return fmt.Errorf("SecretGopher: the piles do not match the deck for policy %d", p)
+		}
+	}
+	// the tracks count the enacted policies, and reach the length of a track only once the game is over
+	for p, n := range s.Tracks {
+		enacted := 0
+		for _, e := range s.Enacted {
+			if int(e) == p {
+				enacted++
+			}
+		}
+		if int(n) != enacted {
+			return fmt.Errorf("SecretGopher: invalid track of policy %d", p)
+		}
+	}
+	for _, w := range s.Rules.Wins {
+		if w.Kind == TrackFilled && (s.Tracks[w.Policy] > w.Count || s.Tracks[w.Policy] == w.Count && s.State != gameEnd) {
+			return fmt.Errorf("SecretGopher: invalid track of policy %d", w.Policy)
+		}
+	}
+	return nil
+}
+
+// seats tells if every seat in seats is taken at the table, or is NotSet when unset is true
+func (s *snapshot) seats(unset bool, seats ...int8) bool {
+	for _, p := range seats {
+		if (p < 0 || p >= s.Players) && !(unset && p == NotSet) {
+			return false
+		}
+	}
+	return true
+}
+
+// Snapshot serializes the whole internal state of the game to JSON.
+// The result can be turned back into a running game by RestoreGame
+func (g *Game) Snapshot() ([]byte, error) {
+	o, _ := g.send(context.Background(), snapshotGame{})
+	s, ok := o.(snapshot)
+	if !ok {
+		return nil, errors.New("SecretGopher: the game is closed")
+	}
+	return json.Marshal(s)
+}
+
+// RestoreGame rebuilds a game from a Snapshot and subscribes it to a handler.
+// Seeded and crypto/rand games keep their random source, while games created with a custom Source
+// continue with a source seeded with the current time.
+// The deadlines of the restored game are measured by the system clock and start over from the current phase.
+// The outputs of the restored log entries are kept as raw JSON
+func RestoreGame(b []byte) (Game, error) {
+	var s struct {
+		snapshot
+		Log []struct {
+			Seq     uint64
+			Command Command
+			Output  json.RawMessage
+		}
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return Game{}, err
+	}
+	if err := s.check(); err != nil {
+		return Game{}, err
+	}
+	G := Game{
+		data: &gameData{
+			seed:          s.Seed,
+			seeded:        s.Seeded,
+			crypto:        s.CryptoRand,
+			state:         s.State,
+			players:       s.Players,
+			deck:          deck{pile: s.Pile, discarded: s.Discarded, enacted: s.Enacted},
+			president:     s.President,
+			chancellor:    s.Chancellor,
+			roles:         s.Roles,
+			nextPresident: s.NextPresident,
+			oldGov:        s.OldGov,
+			investigated:  s.Investigated,
+			investigators: s.Investigators,
+			votes:         s.Votes,
+			voted:         s.Voted,
+			killed:        s.Killed,
+			policyChoice:  s.PolicyChoice,
+			vetoDenied:    s.VetoDenied,
+			eTracker:      s.ETracker,
+			tracks:        s.Tracks,
+			log:           make([]LogEntry, len(s.Log)),
+			timeouts:      s.Timeouts,
+			rules:         s.Rules,
+			identities:    s.Identities,
+			host:          s.Host,
+			ready:         s.Ready,
+			autoStart:     s.AutoStart,
+			clock:         systemClock{},
+			eventBuffer:   defaultEventBuffer,
+			timedState:    waitingPlayers,
+		},
+	}
+	for i, e := range s.Log {
+		G.data.log[i] = LogEntry{Seq: e.Seq, Command: e.Command, Output: e.Output}
+	}
+	switch {
+	case s.CryptoRand:
+		G.data.rng = rand.New(cryptoSource{})
+	case s.Seeded:
+		G.data.source = newCountingSource(s.Seed, s.RandomCalls)
+		G.data.rng = rand.New(G.data.source)
+	default:
+		G.data.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	G.data.deck.rng = G.data.rng
+	G.data.lastActive = G.data.clock.Now()
+	G.subscribeHandler()
+	G.StateFor(Spectator) // let the handler arm the deadline of the restored phase
+	return G, nil
+}
@@ -0,0 +1,371 @@
+package SecretGopher
+
+import "math/rand"
+
+// Difficulty tunes how well a StrategyBot plays
+type Difficulty uint8
+
+const (
+	Easy   Difficulty = iota // Easy bots often play at random, only judge the others by the policies they enact and never lie
+	Normal                   // Normal bots sometimes play at random, read the claims and lie about their hands
+	Hard                     // Hard bots never play at random, read the votes too, and pass liberal policies to win back trust
+)
+
+// blunders maps every difficulty to the odds of a bot playing a random legal command
+var blunders = [...]float64{Easy: 0.3, Normal: 0.1, Hard: 0}
+
+// StrategyBot is a Bot that plays its role.
+// Liberals keep track of how suspicious every player is, from the policies enacted by their governments, from
+// their claims and from the governments they voted for.
+// The other parties push their policies when they can get away with it, lie about their hands and protect each
+// other, while a leader who wins by being elected chancellor (i.e. Hitler) plays like a liberal to stay out of
+// sight, until his election would win the game.
+// A StrategyBot plays a single seat of a single game
+type StrategyBot struct {
+	level     Difficulty
+	rng       *rand.Rand
+	random    *RandomBot
+	suspicion []float64 // suspicion maps every seat to how much a liberal would distrust it, given what is public
+	gov       []int8    // gov is the government of the ongoing legislative session, if any
+	votes     []Vote    // votes are the votes that elected gov
+	heard     int       // heard is the number of claims already taken into account
+	told      *Claim    // told is the claim of the president of the last session, waiting for the chancellor's
+}
+
+// NewStrategyBot creates a StrategyBot playing at level, drawing its choices from a source seeded with seed
+func NewStrategyBot(seed int64, level Difficulty) *StrategyBot {
+	return &StrategyBot{level: level, rng: rand.New(rand.NewSource(seed)), random: NewRandomBot(seed)}
+}
+
+// Act plays the first legal action the way the role of the bot calls for
+func (b *StrategyBot) Act(o Observation) Command {
+	b.read(o)
+	if b.rng.Float64() < blunders[b.level] {
+		return b.random.Act(o)
+	}
+	a := o.Actions[0]
+	switch a.Kind {
+	case MakeChancellorCommand:
+		return a.Command(o.Seat, b.nominate(o, a.Choices))
+	case VoteCommand:
+		c := a.Command(o.Seat, 0)
+		c.Vote = b.vote(o)
+		return c
+	case PolicyDiscardCommand:
+		if len(o.Actions) > 1 && !contains(o.Hand, b.pushes(o)) {
+			return o.Actions[1].Command(o.Seat, 0) // veto a hand with nothing worth enacting
+		}
+		return a.Command(o.Seat, b.discard(o))
+	case SpecialPowerCommand:
+		return a.Command(o.Seat, b.target(o, a))
+	}
+	return b.random.Act(o)
+}
+
+// Claim tells the truth, unless the bot is hiding the liberal policies it got rid of
+func (b *StrategyBot) Claim(o Observation, enacted Policy) []Policy {
+	hand := append([]Policy{}, o.Hand...)
+	if b.liberal(o) || b.level == Easy || enacted == LiberalPolicy {
+		return hand
+	}
+	// blame the deck
+	for i, p := range hand {
+		if p == LiberalPolicy {
+			hand[i] = enacted
+		}
+	}
+	return hand
+}
+
+// read updates the suspicions with what happened since the bot last acted
+func (b *StrategyBot) read(o Observation) {
+	if b.suspicion == nil {
+		b.suspicion = make([]float64, len(o.State.Players))
+	}
+	for _, e := range o.Events {
+		b.follow(e.Output)
+	}
+	for ; b.heard < len(o.Claims); b.heard++ {
+		b.hear(o.Claims[b.heard])
+	}
+}
+
+// follow follows the legislative sessions, judging the governments by the policies they enact
+func (b *StrategyBot) follow(out Output) {
+	r, _ := Unpack(out)
+	switch r := r.(type) {
+	case TimeoutApplied:
+		b.follow(r.Result)
+	case LegislationPresident:
+		b.gov = []int8{r.State.President, r.State.Chancellor}
+		b.votes = r.State.Votes
+	case PolicyEnaction:
+		if b.gov != nil {
+			b.judge(r.Enacted)
+		}
+		b.gov = nil
+	case NextPresident, VetoAccepted, GameEnd:
+		b.gov = nil
+	}
+}
+
+// judge blames the government, and at Hard its voters, for the policy it enacted
+func (b *StrategyBot) judge(enacted Policy) {
+	p, c := b.gov[0], b.gov[1]
+	if enacted == LiberalPolicy {
+		b.suspicion[p] -= 0.3
+		b.suspicion[c] -= 0.5
+	} else {
+		b.suspicion[p] += 0.5
+		b.suspicion[c] += 1
+	}
+	if b.level < Hard {
+		return
+	}
+	for s, v := range b.votes {
+		switch {
+		case v == Ja && enacted != LiberalPolicy:
+			b.suspicion[s] += 0.2
+		case v == Nein && enacted == LiberalPolicy:
+			b.suspicion[s] += 0.1
+		}
+	}
+}
+
+// hear compares the claims of the government of a session
+func (b *StrategyBot) hear(c Claim) {
+	if b.level == Easy {
+		return
+	}
+	switch c.Seat {
+	case c.President:
+		b.told = &c
+	case c.Chancellor:
+		cl := count(c.Hand, LiberalPolicy)
+		if cl > 0 && c.Enacted != LiberalPolicy {
+			b.suspicion[c.Seat] += 2 // the chancellor admits discarding a liberal policy
+		}
+		if t := b.told; t != nil && t.President == c.President && t.Chancellor == c.Chancellor {
+			switch pl := count(t.Hand, LiberalPolicy); {
+			case cl > pl || pl >= 2 && cl == 0: // somebody is lying
+				b.suspicion[t.Seat]++
+				b.suspicion[c.Seat]++
+			case pl == 1 && cl == 0: // the president admits discarding the only liberal policy
+				b.suspicion[t.Seat] += 1.5
+			case pl == 0 && c.Enacted != LiberalPolicy: // the deck is to blame
+				b.suspicion[t.Seat] -= 0.4
+				b.suspicion[c.Seat] -= 0.7
+			}
+		}
+		b.told = nil
+	}
+}
+
+// liberal tells if the bot is a liberal
+func (b *StrategyBot) liberal(o Observation) bool {
+	return o.State.Parties[o.Seat] == LiberalMembership
+}
+
+// friend tells if the bot knows s is in its party
+func (b *StrategyBot) friend(o Observation, s int8) bool {
+	return s == o.Seat || o.State.Parties[s] == o.State.Parties[o.Seat]
+}
+
+// leader tells if the bot wins by being elected chancellor
+func (b *StrategyBot) leader(o Observation) bool {
+	for _, w := range o.Rules.Wins {
+		if w.Kind == ChancellorElected && w.Role == o.State.Roles[o.Seat] {
+			return true
+		}
+	}
+	return false
+}
+
+// elects tells if electing s chancellor would win the game for the party of mine, who must be known to the bot
+func (b *StrategyBot) elects(o Observation, s int8, mine bool) bool {
+	for _, w := range o.Rules.Wins {
+		if w.Kind != ChancellorElected || o.State.Tracks[w.Policy] < w.Count {
+			continue
+		}
+		if ours := o.Rules.PartyOf(w.Role) == o.State.Parties[o.Seat]; ours != mine {
+			continue
+		}
+		// the leader of another party could hide behind any unknown role
+		if o.State.Roles[s] == w.Role || !mine && o.State.Roles[s] == UnknownRole {
+			return true
+		}
+	}
+	return false
+}
+
+// completes tells if enacting one more policy of kind p would win the game
+func (b *StrategyBot) completes(o Observation, p Policy) bool {
+	for _, w := range o.Rules.Wins {
+		if w.Kind == TrackFilled && w.Policy == p && o.State.Tracks[p] == w.Count-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// pushes returns the kind of policy the bot wants enacted.
+// Every party pushes the policies whose track wins it the game, but a leader hides behind liberal policies until
+// his own win the game. A party that cannot win by filling a track plays like a liberal
+func (b *StrategyBot) pushes(o Observation) Policy {
+	own := LiberalPolicy
+	for _, w := range o.Rules.Wins {
+		if w.Kind == TrackFilled && w.Ending.Winner() == o.State.Parties[o.Seat] {
+			own = w.Policy
+			break
+		}
+	}
+	if b.leader(o) && !b.completes(o, own) {
+		return LiberalPolicy
+	}
+	return own
+}
+
+// distrust returns how much the bot distrusts s, knowing what it knows
+func (b *StrategyBot) distrust(o Observation, s int8) float64 {
+	switch {
+	case b.friend(o, s):
+		return -5
+	case o.State.Parties[s] != UnknownParty:
+		return 5
+	}
+	return b.suspicion[s]
+}
+
+// nominate picks the chancellor nominee
+func (b *StrategyBot) nominate(o Observation, choices []int8) int8 {
+	for _, s := range choices {
+		if b.elects(o, s, true) {
+			return s
+		}
+	}
+	if b.liberal(o) {
+		return b.least(choices, func(s int8) float64 { return b.distrust(o, s) })
+	}
+	return b.least(choices, func(s int8) float64 {
+		d := b.suspicion[s]
+		// the party gets its members into government, as long as they do not look suspicious
+		if !b.leader(o) && b.friend(o, s) && d < 1 {
+			d -= 2
+		}
+		return d
+	})
+}
+
+// vote returns the vote of the bot on the nominated government, or on the proposed veto
+func (b *StrategyBot) vote(o Observation) Vote {
+	if o.State.Phase == VetoPresidentPhase {
+		if contains(o.Hand, b.pushes(o)) {
+			return Nein
+		}
+		return Ja
+	}
+	p, c := o.State.President, o.State.Chancellor
+	if p == o.Seat || c == o.Seat || b.elects(o, c, true) {
+		return Ja
+	}
+	if !b.liberal(o) && !b.leader(o) && (b.friend(o, p) || b.friend(o, c)) {
+		return Ja
+	}
+	// the others judge the government the way a liberal would
+	score := b.suspicion
+	if b.liberal(o) {
+		score = make([]float64, len(b.suspicion))
+		for s := range score {
+			score[s] = b.distrust(o, int8(s))
+		}
+	}
+	limit := 1.0
+	if o.State.ElectionTracker == o.Rules.ChaosThreshold-1 {
+		limit++ // a policy enacted by chaos is worse than a doubtful government
+	}
+	if b.elects(o, c, false) {
+		limit = 0 // a mistake would lose the game
+	}
+	if score[p]/2+score[c] < limit {
+		return Ja
+	}
+	return Nein
+}
+
+// discard returns the index of the policy to discard
+func (b *StrategyBot) discard(o Observation) int8 {
+	keep := b.pushes(o)
+	if !b.liberal(o) && b.level == Hard && o.State.Phase == ChancellorLegislationPhase && b.suspicion[o.Seat] > 1.5 &&
+		!b.completes(o, keep) && !b.completes(o, LiberalPolicy) {
+		keep = LiberalPolicy // win back the trust of the liberals
+	}
+	for i, p := range o.Hand {
+		if p != keep {
+			return int8(i)
+		}
+	}
+	return 0
+}
+
+// target returns the target of the power of a
+func (b *StrategyBot) target(o Observation, a Action) int8 {
+	if len(a.Choices) == 0 {
+		return 0
+	}
+	switch {
+	case b.liberal(o) && a.Power == Election:
+		return b.least(a.Choices, func(s int8) float64 { return b.distrust(o, s) })
+	case b.liberal(o):
+		return b.most(a.Choices, func(s int8) float64 { return b.distrust(o, s) })
+	case a.Power == Election:
+		return b.least(a.Choices, func(s int8) float64 { return b.distrust(o, s) })
+	}
+	// the others spare their party, and get rid of the most trusted liberals when they can
+	return b.least(a.Choices, func(s int8) float64 {
+		switch {
+		case b.friend(o, s):
+			return 100
+		case a.Power == Execution && !b.leader(o):
+			return b.suspicion[s]
+		}
+		return -b.suspicion[s]
+	})
+}
+
+// least returns the choice scoring the least, breaking ties at random
+func (b *StrategyBot) least(choices []int8, score func(int8) float64) int8 {
+	best, ties := choices[0], 1
+	for _, c := range choices[1:] {
+		switch d, m := score(c), score(best); {
+		case d < m:
+			best, ties = c, 1
+		case d == m:
+			ties++
+			if b.rng.Intn(ties) == 0 {
+				best = c
+			}
+		}
+	}
+	return best
+}
+
+// most returns the choice scoring the most, breaking ties at random
+func (b *StrategyBot) most(choices []int8, score func(int8) float64) int8 {
+	return b.least(choices, func(s int8) float64 { return -score(s) })
+}
+
+// contains tells if the hand contains a policy of kind p
+func contains(hand []Policy, p Policy) bool {
+	return count(hand, p) > 0
+}
+
+// count returns the number of policies of kind p in the hand
+func count(hand []Policy, p Policy) int {
+	n := 0
+	for _, h := range hand {
+		if h == p {
+			n++
+		}
+	}
+	return n
+}
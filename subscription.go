@@ -0,0 +1,125 @@
+package SecretGopher
+
+import "context"
+
+// defaultEventBuffer is the size of the subscription buffers when Options.EventBuffer is not set
+const defaultEventBuffer = 64
+
+// Event is a change in the state of a game, as seen by one of its subscribers
+type Event struct {
+	Seq    uint64    // Seq is the sequence number of the log entry that caused the change
+	Output Output    // Output is the Output of the change, stripped of what the subscriber cannot see
+	State  GameState // State is the state of the game after the change, as seen by the subscriber
+}
+
+// subscriber is a channel receiving the events of a game as seen by viewer
+type subscriber struct {
+	viewer int8
+	ch     chan Event
+}
+
+// Subscribe returns a channel that receives every change of the game as seen by player.
+// Hands, peeked policies and investigation results only reach the players entitled to see them.
+//
+// The channel is buffered (see Options.EventBuffer) and the game never waits for a subscriber:
+// a subscriber that falls behind by a whole buffer is dropped and its channel closed, it may then
+// subscribe again and catch up using StateFor. The channel is also closed when the game ends,
+// right after the GameEnd event, when Unsubscribe is called or when the game is closed
+func (g *Game) Subscribe(player int8) <-chan Event {
+	ch := make(chan Event, g.data.eventBuffer)
+	if o, _ := g.send(context.Background(), subscribe{Viewer: player, ch: ch}); !isOk(o) {
+		close(ch) // the game is closed
+	}
+	return ch
+}
+
+// SubscribeSpectator returns a channel that receives every change of the game as seen by a spectator.
+// It behaves like Subscribe
+func (g *Game) SubscribeSpectator() <-chan Event {
+	return g.Subscribe(Spectator)
+}
+
+// Unsubscribe stops the delivery of events to ch and closes it
+func (g *Game) Unsubscribe(ch <-chan Event) {
+	g.send(context.Background(), unsubscribe{ch: ch})
+}
+
+// unsubscribe removes the subscriber receiving on ch, if any, and closes its channel
+func (g *gameData) unsubscribe(ch <-chan Event) {
+	for i, s := range g.subscribers {
+		if s.ch == ch {
+			close(s.ch)
+			g.subscribers = append(g.subscribers[:i], g.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// publish delivers the log entry to every subscriber, dropping the ones whose buffer is full
+func (g *gameData) publish(e LogEntry) {
+	kept := g.subscribers[:0]
+	for _, s := range g.subscribers {
+		select {
+		case s.ch <- Event{Seq: e.Seq, Output: g.redact(e.Output, s.viewer, e.Command.Caller), State: g.stateFor(s.viewer)}:
+			kept = append(kept, s)
+		default:
+			close(s.ch) // the subscriber fell behind
+		}
+	}
+	g.subscribers = kept
+	if g.state == gameEnd {
+		for _, s := range g.subscribers {
+			close(s.ch)
+		}
+		g.subscribers = nil
+	}
+}
+
+// reply strips output o of event e of the information the caller of e cannot see.
+// The outputs of the events that are not sent by a player, like timeouts and views, are left whole
+func (g *gameData) reply(e event, o Output) Output {
+	c, ok := commandOf(e)
+	if !ok || c.Kind == TimeoutCommand {
+		return o
+	}
+	return g.redact(o, c.Caller, c.Caller)
+}
+
+// redact strips output o of the information viewer cannot see.
+// caller is the player whose command produced the output
+func (g *gameData) redact(o Output, viewer, caller int8) Output {
+	ok, isOk := o.(Ok)
+	if !isOk {
+		return o
+	}
+	switch info := ok.Info.(type) {
+	case LegislationPresident:
+		if viewer != g.president {
+			info.Hand = nil
+		}
+		return Ok{Info: info}
+	case LegislationChancellor:
+		if viewer != g.chancellor {
+			info.Hand = nil
+		}
+		return Ok{Info: info}
+	case VetoProposed:
+		if viewer != g.president && viewer != g.chancellor {
+			info.Hand = nil
+		}
+		return Ok{Info: info}
+	case SpecialPowerFeedback:
+		// the feedback of a power is only for the president who used it
+		if viewer != caller {
+			info.Feedback = nil
+		}
+		return Ok{Info: info}
+	case TimeoutApplied:
+		if n := len(info.Commands); n > 0 {
+			caller = info.Commands[n-1].Caller
+		}
+		info.Result = g.redact(info.Result, viewer, caller)
+		return Ok{Info: info}
+	}
+	return o
+}
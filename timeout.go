@@ -0,0 +1,123 @@
+package SecretGopher
+
+import "time"
+
+// Timeouts configures the deadlines of the game phases.
+// A zero duration means the phase has no deadline
+type Timeouts struct {
+	Nomination  time.Duration // Nomination is the time the president has to nominate a chancellor
+	Election    time.Duration // Election is the time the players have to vote a government
+	Legislation time.Duration // Legislation is the time the president and the chancellor each have to discard a policy
+	Power       time.Duration // Power is the time the president has to use a special power
+	Veto        time.Duration // Veto is the time a veto has to be answered
+	MissingVote Vote          // MissingVote is cast for the players who did not vote in time. Any vote but Ja and Nein makes them abstain
+}
+
+// of returns the deadline of state s
+func (t Timeouts) of(s state) time.Duration {
+	switch s {
+	case chancellorCandidacy:
+		return t.Nomination
+	case governmentElection:
+		return t.Election
+	case presidentLegislation, chancellorLegislation:
+		return t.Legislation
+	case specialPeek, specialInvestigate, specialElection, specialExecution:
+		return t.Power
+	case vetoPresident:
+		return t.Veto
+	}
+	return 0
+}
+
+// schedule arms the deadline of the current phase every time the phase changes
+func (h *handlerSubscription) schedule(g *gameData) {
+	if g.state == g.timedState {
+		return
+	}
+	g.timedState = g.state
+	g.deadline++
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	if d := g.timeouts.of(g.state); d > 0 {
+		deadline := g.deadline
+		g.timer = g.clock.AfterFunc(d, func() { h.expire(g, deadline) })
+	}
+}
+
+// expire sends the timeout of the given deadline to the handler, and notifies the game when it gets applied.
+// It runs in the goroutine of the timer
+func (h *handlerSubscription) expire(g *gameData, deadline uint64) {
+	reply := make(chan Output, 1)
+	select {
+	case h.in <- input{gameData: g, event: timeout{Deadline: deadline}, reply: reply}:
+	case <-h.done:
+		return // the handler was stopped
+	}
+	if o, ok := (<-reply).(Ok); ok && g.onTimeout != nil {
+		g.onTimeout(o.Info.(TimeoutApplied))
+	}
+}
+
+// applyTimeout takes the default actions of the current phase on behalf of the players that did not act in time
+func (h *handlerSubscription) applyTimeout(g *gameData, out chan<- Output) {
+	var t TimeoutApplied
+	res := make(chan Output, 1) // res holds the output of the default actions
+	// send sends a command on behalf of a late player
+	send := func(c Command) {
+		t.Players = append(t.Players, c.Caller)
+		t.Commands = append(t.Commands, c)
+		h.handleEvent(g, c.event(), res)
+		t.Result = <-res
+	}
+	switch g.state {
+	case chancellorCandidacy:
+		send(Command{Kind: MakeChancellorCommand, Caller: g.president, Proposal: g.randomPlayer(g.nomineeCode)})
+	case governmentElection:
+		if v := g.timeouts.MissingVote; v == Ja || v == Nein {
+			for _, p := range g.livingPlayers() {
+				if g.votes[p] == NoVote {
+					send(Command{Kind: VoteCommand, Caller: p, Vote: v})
+				}
+			}
+		} else {
+			// the late players abstain
+			for _, p := range g.livingPlayers() {
+				if g.votes[p] == NoVote {
+					t.Players = append(t.Players, p)
+				}
+			}
+			h.closeElection(g, res)
+			t.Result = <-res
+		}
+	case presidentLegislation:
+		send(Command{Kind: PolicyDiscardCommand, Caller: g.president, Selection: int8(g.rng.Intn(len(g.policyChoice)))})
+	case chancellorLegislation:
+		send(Command{Kind: PolicyDiscardCommand, Caller: g.chancellor, Selection: int8(g.rng.Intn(len(g.policyChoice)))})
+	case specialPeek:
+		send(Command{Kind: SpecialPowerCommand, Caller: g.president, Power: Peek})
+	case specialInvestigate:
+		send(Command{Kind: SpecialPowerCommand, Caller: g.president, Power: Investigate, Selection: g.randomPlayer(g.investigateCode)})
+	case specialElection:
+		send(Command{Kind: SpecialPowerCommand, Caller: g.president, Power: Election, Selection: g.randomPlayer(g.powerTargetCode)})
+	case specialExecution:
+		send(Command{Kind: SpecialPowerCommand, Caller: g.president, Power: Execution, Selection: g.randomPlayer(g.powerTargetCode)})
+	case vetoPresident:
+		send(Command{Kind: VoteCommand, Caller: g.president, Vote: Nein})
+	default:
+		out <- Error{Err: WrongPhase{because(DeadlineOver, "")}} // send out error
+		return
+	}
+	out <- Ok{Info: t}
+}
+
+// randomPlayer picks a random player among the ones code has no objection to, or NotSet if there is none
+func (g *gameData) randomPlayer(code func(int8) Code) int8 {
+	c := g.choices(code)
+	if len(c) == 0 {
+		return NotSet
+	}
+	return c[g.rng.Intn(len(c))]
+}